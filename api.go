@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ============================================================================
+// REST JOB API
+// ============================================================================
+
+// handleCompile starts a job from a CompileRequest (POST /api/compile).
+// The job runs in the background; poll GET /api/jobs/{id} for its progress.
+func handleCompile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CompileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	job, err := newExecutionJob(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkModelInstalled(job); err != nil {
+		job.Cancel()
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	jobRegistry.Add(job)
+	fmt.Printf("[Job %s] Started via API (language=%s, model=%s, provider=%s)\n", job.ID, job.Language, job.Model, job.Provider)
+	go RunCompilationJob(job, newJobSink(job, nil))
+
+	writeJSON(w, http.StatusAccepted, CompileResponse{
+		JobID:  job.ID,
+		Status: job.snapshot().Status,
+	})
+}
+
+// handleJob serves GET /api/jobs/{id} (status and results) and
+// DELETE /api/jobs/{id} (cancel).
+func handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		writeAPIError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	job, ok := jobRegistry.Get(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, job.snapshot())
+
+	case http.MethodDelete:
+		if job.isFinished() {
+			writeAPIError(w, http.StatusConflict, "job already finished")
+			return
+		}
+		job.Cancel()
+		writeJSON(w, http.StatusAccepted, CompileResponse{
+			JobID:  job.ID,
+			Status: "cancelling",
+		})
+
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
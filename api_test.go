@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func postCompile(t *testing.T, server *httptest.Server, req CompileRequest) (*http.Response, CompileResponse) {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(server.URL+"/api/compile", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/compile failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var compileResp CompileResponse
+	json.NewDecoder(resp.Body).Decode(&compileResp)
+	return resp, compileResp
+}
+
+// waitForJob polls the job API until the job leaves the running state
+func waitForJob(t *testing.T, server *httptest.Server, id string) JobStatusResponse {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(server.URL + "/api/jobs/" + id)
+		if err != nil {
+			t.Fatalf("GET /api/jobs/%s failed: %v", id, err)
+		}
+		var status JobStatusResponse
+		json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+
+		if status.Status == "completed" || status.Status == "aborted" {
+			return status
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return JobStatusResponse{}
+}
+
+func TestCompileAPIRejectsInvalidRequest(t *testing.T) {
+	server := httptest.NewServer(newMux())
+	defer server.Close()
+
+	resp, _ := postCompile(t, server, CompileRequest{Language: "go"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty prompt, got %d", resp.StatusCode)
+	}
+
+	getResp, err := http.Get(server.URL + "/api/compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	getResp.Body.Close()
+	if getResp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET /api/compile, got %d", getResp.StatusCode)
+	}
+}
+
+func TestJobAPIUnknownJob(t *testing.T) {
+	server := httptest.NewServer(newMux())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/jobs/does-not-exist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestJobAPILifecycle(t *testing.T) {
+	release := make(chan struct{})
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode(chatReply(""))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	server := httptest.NewServer(newMux())
+	defer server.Close()
+
+	resp, compileResp := postCompile(t, server, CompileRequest{Language: "go", Prompt: "Add two numbers"})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+	if compileResp.JobID == "" {
+		t.Fatal("Expected a job ID")
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/jobs/"+compileResp.JobID, nil)
+	delResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delResp.Body.Close()
+	if delResp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected 202 for DELETE, got %d", delResp.StatusCode)
+	}
+	close(release)
+
+	status := waitForJob(t, server, compileResp.JobID)
+	if status.Status != "aborted" {
+		t.Errorf("Expected aborted job, got %q", status.Status)
+	}
+
+	delResp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delResp.Body.Close()
+	if delResp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for DELETE of finished job, got %d", delResp.StatusCode)
+	}
+}
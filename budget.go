@@ -0,0 +1,162 @@
+package main
+
+import (
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"strings"
+)
+
+// ============================================================================
+// TOKEN BUDGET
+// ============================================================================
+
+const (
+	// OllamaDefaultNumCtx is the context Ollama allocates when a request
+	// doesn't set num_ctx. The model never sees more than this, whatever it
+	// was trained for; anything beyond is silently cut off the front.
+	OllamaDefaultNumCtx = 4096
+
+	// ResponseTokenReserve is kept free for the model's answer
+	ResponseTokenReserve = 1024
+
+	// ContextCompactionThreshold is the share of the budget at which the
+	// conversation is rebuilt from a compact history
+	ContextCompactionThreshold = 0.8
+
+	// charsPerToken is a rough average for English text and code
+	charsPerToken = 4
+)
+
+// modelContextWindows are the context lengths models were trained for, in
+// tokens, by full name or by family (the name without its tag)
+var modelContextWindows = map[string]int{
+	"llama3.1":      131072,
+	"llama3.2":      131072,
+	"deepseek-r1":   131072,
+	"codellama":     16384,
+	"qwen2.5-coder": 32768,
+}
+
+// contextWindow returns the number of tokens the model actually gets to see:
+// num_ctx if the job's options set it, otherwise what Ollama allocates by
+// default, but never more than the model was trained for
+func contextWindow(model string, options *ollamaimplementation.Options) int {
+	window, ok := modelContextWindows[model]
+	if !ok {
+		window, ok = modelContextWindows[strings.SplitN(model, ":", 2)[0]]
+	}
+
+	allocated := OllamaDefaultNumCtx
+	if options != nil && options.NumCtx != nil {
+		allocated = *options.NumCtx
+	}
+	if !ok || window > allocated {
+		return allocated
+	}
+	return window
+}
+
+// contextBudget is how many tokens a prompt and the conversation carried
+// along with it may take up
+func contextBudget(model string, options *ollamaimplementation.Options) int {
+	return contextWindow(model, options) - ResponseTokenReserve
+}
+
+// estimateTokens approximates the token count of s without a tokenizer
+func estimateTokens(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
+
+// estimateMessageTokens approximates the token count of a conversation
+func estimateMessageTokens(messages []ollamaimplementation.Message) int {
+	tokens := 0
+	for _, m := range messages {
+		tokens += estimateTokens(m.Content)
+	}
+	return tokens
+}
+
+// ============================================================================
+// PROMPT PLANNING
+// ============================================================================
+
+// promptPlan is the prompt for an iteration and how it was fitted into the budget
+type promptPlan struct {
+	Prompt        string
+	PromptTokens  int    // Estimated tokens of Prompt
+	ContextTokens int    // Conversation tokens sent along with it
+	SystemTokens  int    // System prompt tokens, sent with every request
+	ResetContext  bool   // The carried conversation must be dropped
+	Detail        string // Detail level of the repair prompt
+
+	// Patch is set when Prompt asks for a diff against the last attempt.
+	// Fallback then asks for the full code instead, for when the diff
+	// doesn't apply.
+	Patch    bool
+	Fallback string
+}
+
+// Tokens is the estimated size of the whole request
+func (p promptPlan) Tokens() int {
+	return p.PromptTokens + p.ContextTokens + p.SystemTokens
+}
+
+// planPrompt builds the prompt for an iteration. While the full prompt and
+// the carried conversation fit comfortably in the model's budget and the
+// prompt hasn't grown past MaxPromptSizeGrowthRate, that is what's sent.
+// Otherwise the conversation is dropped and rebuilt from a summary of the
+// earlier attempts, with less and less detail about the last one until the
+// prompt fits. If nothing fits, the smallest prompt is returned and the
+// caller decides whether to abort.
+func planPrompt(job *ExecutionJob, iteration int) promptPlan {
+	limit := int(float64(contextBudget(job.Model, job.Options)) * ContextCompactionThreshold)
+	growthCap := int(float64(previousPromptTokens(job, iteration)) * MaxPromptSizeGrowthRate)
+	fits := func(p promptPlan) bool {
+		return p.Tokens() <= limit && (growthCap == 0 || p.PromptTokens <= growthCap)
+	}
+	system := estimateTokens(systemMessage(job))
+	compose := func(detail promptDetail, withHistory bool) promptPlan {
+		patch := wantsPatch(job, iteration, detail)
+		prompt := composePrompt(job, iteration, detail, withHistory, patch)
+		p := promptPlan{Prompt: prompt, PromptTokens: estimateTokens(prompt), SystemTokens: system, Detail: detail.name, Patch: patch}
+		if patch {
+			p.Fallback = composePrompt(job, iteration, detail, withHistory, false)
+		}
+		return p
+	}
+	carried := estimateMessageTokens(job.LLMCtx.Messages)
+
+	plan := compose(detailFull, false)
+	plan.ContextTokens = carried
+	if fits(plan) {
+		return plan
+	}
+
+	for _, detail := range []promptDetail{detailFull, detailCompact, detailMinimal} {
+		plan = compose(detail, true)
+		plan.ResetContext = carried > 0
+		if fits(plan) {
+			break
+		}
+	}
+	return plan
+}
+
+// previousPromptTokens returns the size of the last repair prompt, the
+// baseline for the growth limit. The first prompt has no feedback in it, so
+// growth is only measured between repair prompts.
+func previousPromptTokens(job *ExecutionJob, iteration int) int {
+	if iteration <= 2 || len(job.Metrics.PromptTokens) == 0 {
+		return 0
+	}
+	return job.Metrics.PromptTokens[len(job.Metrics.PromptTokens)-1]
+}
+
+// promptGrowth is how much the prompt grew since the previous repair prompt,
+// or 0 if there is nothing to compare with
+func promptGrowth(job *ExecutionJob, iteration, tokens int) float64 {
+	previous := previousPromptTokens(job, iteration)
+	if previous == 0 {
+		return 0
+	}
+	return float64(tokens) / float64(previous)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"strings"
+	"testing"
+)
+
+func TestContextWindow(t *testing.T) {
+	modelContextWindows["tiny"] = 2048
+	defer delete(modelContextWindows, "tiny")
+
+	tests := map[string]int{
+		"llama3.2:1b":     OllamaDefaultNumCtx, // Trained for more than Ollama gives it
+		"codellama:13b":   OllamaDefaultNumCtx,
+		"tiny:latest":     2048,
+		"no-such-model:7": OllamaDefaultNumCtx,
+	}
+	for model, want := range tests {
+		if got := contextWindow(model, nil); got != want {
+			t.Errorf("contextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+
+	numCtx := 16384
+	options := &ollamaimplementation.Options{NumCtx: &numCtx}
+	sized := map[string]int{
+		"llama3.2:1b":     16384,
+		"tiny:latest":     2048, // num_ctx can't add to what the model was trained for
+		"no-such-model:7": 16384,
+	}
+	for model, want := range sized {
+		if got := contextWindow(model, options); got != want {
+			t.Errorf("contextWindow(%q, num_ctx %d) = %d, want %d", model, numCtx, got, want)
+		}
+	}
+}
+
+// budgetJob is a Go job whose last attempt failed with a type error in
+// mainCode, carrying a conversation of about conversation tokens
+func budgetJob(t *testing.T, mainCode string, conversation int) *ExecutionJob {
+	t.Helper()
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Model: "llama3.2:1b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(job.Cancel)
+
+	result := &CompilationResult{
+		ErrorType:   ErrorTypeType,
+		Diagnostics: []Diagnostic{{File: "main.go", Line: 3, Message: "undefined: c"}},
+	}
+	job.LLMCtx.Attempts = []Attempt{
+		{Iteration: 1, MainCode: "package main\n", Result: &CompilationResult{ErrorType: ErrorTypeSyntax, CompileErrors: []string{"main.go:1:1: expected 'package'"}}},
+		{Iteration: 2, MainCode: mainCode, TestCode: "package main\n", Result: result},
+	}
+	if conversation > 0 {
+		job.LLMCtx.Messages = []ollamaimplementation.Message{
+			{Role: ollamaimplementation.RoleAssistant, Content: strings.Repeat("x", conversation*charsPerToken)},
+		}
+	}
+	return job
+}
+
+func TestPlanPromptKeepsContextWhileItFits(t *testing.T) {
+	job := budgetJob(t, "package main\n\nfunc Add(a, b int) int { return a + c }\n", 500)
+
+	plan := planPrompt(job, 3)
+	if plan.ResetContext || plan.Detail != "full" || plan.ContextTokens != 500 {
+		t.Errorf("plan = reset %v, detail %s, context %d; want the full prompt with context", plan.ResetContext, plan.Detail, plan.ContextTokens)
+	}
+	if strings.Contains(plan.Prompt, "EARLIER ATTEMPTS") {
+		t.Errorf("history summarized although the conversation is kept:\n%s", plan.Prompt)
+	}
+}
+
+func TestPlanPromptCompactsNearBudget(t *testing.T) {
+	job := budgetJob(t, "package main\n\nfunc Add(a, b int) int { return a + c }\n", contextBudget("llama3.2:1b", nil))
+
+	plan := planPrompt(job, 3)
+	if !plan.ResetContext || plan.ContextTokens != 0 {
+		t.Fatalf("conversation not dropped: %+v", plan)
+	}
+	if !strings.Contains(plan.Prompt, "=== EARLIER ATTEMPTS ===\n- Iteration 1: syntax, 1 error(s), first: main.go:1:1: expected 'package'\n") {
+		t.Errorf("compact history missing:\n%s", plan.Prompt)
+	}
+	if !strings.Contains(plan.Prompt, "undefined: c") {
+		t.Errorf("last attempt's errors missing:\n%s", plan.Prompt)
+	}
+}
+
+func TestPlanPromptReducesDetail(t *testing.T) {
+	huge := "package main\n\n" + strings.Repeat("// padding to blow the budget\n", 400)
+	job := budgetJob(t, huge, 0)
+
+	plan := planPrompt(job, 3)
+	if plan.Detail != "minimal" || strings.Contains(plan.Prompt, "Your previous code") {
+		t.Errorf("expected the code to be left out, got %s detail:\n%s", plan.Detail, plan.Prompt)
+	}
+	if plan.Tokens() > contextBudget(job.Model, job.Options) {
+		t.Errorf("minimal prompt still over budget: %d tokens", plan.Tokens())
+	}
+}
+
+func TestPromptGrowth(t *testing.T) {
+	job := &ExecutionJob{Metrics: ExecutionMetrics{PromptTokens: []int{50, 100}}}
+
+	if got := promptGrowth(job, 3, 160); got != 1.6 {
+		t.Errorf("growth = %v, want 1.6", got)
+	}
+	// The first repair prompt is always much bigger than the plain prompt
+	if got := promptGrowth(job, 2, 500); got != 0 {
+		t.Errorf("growth at iteration 2 = %v, want 0", got)
+	}
+}
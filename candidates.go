@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"llama/modules/language"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// CANDIDATES
+// ============================================================================
+
+// candidateTemperatures spread the candidates of an iteration from careful
+// to adventurous
+var candidateTemperatures = []float64{0.2, 0.5, 0.8, 1.0}
+
+// candidate is one generated solution of an iteration and how it fared
+type candidate struct {
+	Iteration int
+	Index     int
+	Options   *ollamaimplementation.Options // The job's options, varied per candidate when there are several
+
+	Messages  []ollamaimplementation.Message // Conversation after this candidate's response, without the system prompt
+	LLMTime   time.Duration
+	MainCode  string
+	TestCode  string
+	AutoFixes []AutoFix
+	Result    *CompilationResult // nil if the candidate never got to compile
+
+	// Extraction is the strategy that recovered the code from the
+	// response, or "patch" if a diff produced it
+	Extraction string
+
+	// In patch mode, the diff that produced the code, or why the diff
+	// couldn't be applied and the code was regenerated in full
+	Patch      string
+	PatchError string
+	Score      Score
+
+	// Set when the candidate failed before compiling
+	AbortCode string // e.g. "llm_error", "extraction_failed"
+	Err       string
+
+	sink MessageSink // Where progress is streamed to while the candidate runs
+}
+
+// candidateOptions returns a candidate's options: the job's, varied across
+// candidates when there is more than one. A temperature the job leaves open
+// is spread over candidateTemperatures and one it sets is kept. The seed is
+// the job's plus index if it sets one, so the first candidate uses it as
+// given, and otherwise depends only on iteration and index, so runs can be
+// repeated either way.
+func candidateOptions(options *ollamaimplementation.Options, iteration, index, count int) *ollamaimplementation.Options {
+	options = options.Merge(nil)
+	if count <= 1 {
+		return options
+	}
+	if options == nil {
+		options = &ollamaimplementation.Options{}
+	}
+	if options.Temperature == nil {
+		temperature := candidateTemperatures[index%len(candidateTemperatures)]
+		options.Temperature = &temperature
+	}
+	seed := iteration*100 + index
+	if options.Seed != nil {
+		seed = *options.Seed + index
+	}
+	options.Seed = &seed
+	return options
+}
+
+// runCandidates generates, extracts and compiles the iteration's candidates
+// concurrently, each in its own workspace, and streams each outcome to the
+// client as it comes in when there is more than one
+func runCandidates(job *ExecutionJob, sink MessageSink, iteration int, plan promptPlan) []*candidate {
+	count := job.Candidates
+	if count < 1 {
+		count = 1
+	}
+
+	candidates := make([]*candidate, count)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := runCandidate(job, sink, iteration, i, plan, candidateOptions(job.Options, iteration, i, count))
+			candidates[i] = c
+			if count > 1 {
+				sendCandidateMessage(sink, iteration, c)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	job.recordCandidates(candidates)
+	return candidates
+}
+
+// runCandidate takes one candidate from prompt to compilation result,
+// streaming the response and each phase to sink as it goes
+func runCandidate(job *ExecutionJob, sink MessageSink, iteration, index int, plan promptPlan, options *ollamaimplementation.Options) *candidate {
+	c := &candidate{Iteration: iteration, Index: index, Options: options, sink: sink}
+	tag := fmt.Sprintf("[Job %s]", job.ID)
+	if job.Candidates > 1 {
+		tag = fmt.Sprintf("[Job %s/%d]", job.ID, index)
+	}
+
+	response, ok := c.generate(job, plan.Prompt)
+	if !ok {
+		fmt.Printf("%s %s\n", tag, c.Err)
+		return c
+	}
+
+	c.sendPhase(PhaseExtracting)
+	var mainCode, testCode string
+	if plan.Patch {
+		var err error
+		mainCode, testCode, c.Patch, err = applyPatch(job.Lang, job.LLMCtx.LastAttempt(), response)
+		if err != nil {
+			fmt.Printf("%s Patch not applied (%v), regenerating the full code\n", tag, err)
+			c.PatchError = err.Error()
+			if response, ok = c.generate(job, plan.Fallback); !ok {
+				fmt.Printf("%s %s\n", tag, c.Err)
+				return c
+			}
+			c.sendPhase(PhaseExtracting)
+		} else {
+			fmt.Printf("%s Patch applied\n", tag)
+			c.Extraction = "patch"
+		}
+	}
+
+	// Extract code from the response
+	if c.Patch == "" {
+		var extractionStrategy string
+		mainCode, testCode, extractionStrategy = job.Lang.Extractor().ExtractWithFallback(response)
+		c.Extraction = extractionStrategy
+		if mainCode == "" {
+			fmt.Printf("%s Extraction failed, no code recovered\n", tag)
+			c.AbortCode, c.Err = "extraction_failed", "Failed to extract code from LLM response"
+			return c
+		}
+		fmt.Printf("%s Extraction strategy: %s\n", tag, extractionStrategy)
+	}
+
+	// Repair mechanical mistakes without another round trip to the LLM
+	if fixer, ok := job.Lang.(language.AutoFixer); ok {
+		mainCode, testCode, c.AutoFixes = fixer.AutoFix(mainCode, testCode)
+		for _, fix := range c.AutoFixes {
+			fmt.Printf("%s Auto-fix %s in %s: %s\n", tag, fix.Name, fix.File, fix.Detail)
+		}
+	}
+	c.MainCode, c.TestCode = mainCode, testCode
+
+	// Compile and test
+	c.sendPhase(PhaseCompiling)
+	compileCtx, cancel := context.WithTimeout(job.Ctx, compileTimeout(job))
+	var result *CompilationResult
+	var err error
+	if reporter, ok := job.Lang.(language.PhaseReporter); ok {
+		result, err = reporter.CompileWithPhases(compileCtx, mainCode, testCode, func(phase string) {
+			// Compiling was reported already
+			if phase != PhaseCompiling {
+				c.sendPhase(phase)
+			}
+		})
+	} else {
+		result, err = job.Lang.Compile(compileCtx, mainCode, testCode)
+	}
+	cancel()
+
+	if err != nil {
+		fmt.Printf("%s Compilation error: %v\n", tag, err)
+		c.AbortCode, c.Err = "compilation_failed", fmt.Sprintf("Compilation failed: %v", err)
+		return c
+	}
+
+	classifyResult(job, result)
+	c.Result = result
+	c.Score = scoreResult(result)
+	return c
+}
+
+// generate sends prompt to the LLM as the next message of the job's
+// conversation, streaming the response as it comes in and adding the time
+// taken to LLMTime. On failure it sets AbortCode and Err and returns false.
+func (c *candidate) generate(job *ExecutionJob, prompt string) (string, bool) {
+	// Candidates share the job's history, so each builds its own slice
+	history := job.LLMCtx.Messages
+	user := ollamaimplementation.Message{Role: ollamaimplementation.RoleUser, Content: prompt}
+	messages := make([]ollamaimplementation.Message, 0, len(history)+2)
+	messages = append(messages, ollamaimplementation.Message{Role: ollamaimplementation.RoleSystem, Content: systemMessage(job)})
+	messages = append(messages, history...)
+	messages = append(messages, user)
+
+	c.sendPhase(PhaseGenerating)
+	llmStart := time.Now()
+	reply, err := llm.Stream(job.Ctx, job.LLM, llm.Request{Model: job.Model, Messages: messages, Options: c.Options}, c.sendToken)
+	c.LLMTime += time.Since(llmStart)
+	response := reply.Content
+
+	switch {
+	case job.Ctx.Err() != nil:
+		c.AbortCode, c.Err = "cancelled", job.Ctx.Err().Error()
+	case errors.Is(err, llm.ErrTimeout):
+		c.AbortCode, c.Err = "llm_timeout", fmt.Sprintf("LLM did not respond within %v", DefaultLLMResponseTime)
+	case errors.Is(err, llm.ErrModelNotFound):
+		c.AbortCode, c.Err = "model_not_found", fmt.Sprintf("Model %s is not available from provider %s", job.Model, job.Provider)
+		if _, ok := llm.Base(job.LLM).(*llm.Ollama); ok {
+			c.Err = fmt.Sprintf("Model %s is not available, pull it with `ollama pull %s`", job.Model, job.Model)
+		}
+	case errors.Is(err, llm.ErrOutOfMemory):
+		c.AbortCode, c.Err = "llm_out_of_memory", fmt.Sprintf("Not enough memory to run %s: %v", job.Model, err)
+	case errors.Is(err, llm.ErrServerUnavailable):
+		c.AbortCode, c.Err = "llm_unavailable", fmt.Sprintf("LLM server of provider %s unavailable: %v", job.Provider, err)
+	case err != nil:
+		c.AbortCode, c.Err = "llm_error", fmt.Sprintf("LLM error: %v", err)
+	case response == "":
+		c.AbortCode, c.Err = "empty_llm_response", "LLM returned empty response"
+	default:
+		c.Messages = append(messages[1:len(messages):len(messages)], ollamaimplementation.Message{Role: ollamaimplementation.RoleAssistant, Content: response})
+		return response, true
+	}
+	return "", false
+}
+
+func (c *candidate) sendPhase(phase string) {
+	c.sink.Send(WSMessage{
+		Type: WSTypePhase,
+		Data: WSPhaseData{Iteration: c.Iteration, Candidate: c.Index, Phase: phase},
+	})
+}
+
+func (c *candidate) sendToken(text string) {
+	c.sink.Send(WSMessage{
+		Type: WSTypeToken,
+		Data: WSTokenData{Iteration: c.Iteration, Candidate: c.Index, Text: text},
+	})
+}
+
+// bestCandidate returns the highest scoring candidate that compiled, the
+// earliest one on a tie, or nil if none did
+func bestCandidate(candidates []*candidate) *candidate {
+	var best *candidate
+	for _, c := range candidates {
+		if c.Result != nil && (best == nil || c.Score.Better(best.Score)) {
+			best = c
+		}
+	}
+	return best
+}
+
+func sendCandidateMessage(sink MessageSink, iteration int, c *candidate) {
+	data := WSCandidateData{
+		Iteration:  iteration,
+		Candidate:  c.Index,
+		Status:     "compiled",
+		Error:      c.Err,
+		MainCode:   c.MainCode,
+		TestCode:   c.TestCode,
+		AutoFixes:  c.AutoFixes,
+		Patch:      c.Patch,
+		PatchError: c.PatchError,
+	}
+	if c.Options != nil {
+		data.Temperature, data.Seed = c.Options.Temperature, c.Options.Seed
+	}
+	if c.Result == nil {
+		data.Status = c.AbortCode
+	} else {
+		score := c.Score
+		data.Score = &score
+		data.ErrorType = c.Result.ErrorType.String()
+	}
+
+	msg := WSMessage{
+		Type: WSTypeCandidate,
+		Data: data,
+	}
+
+	sink.Send(msg)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"llama/modules/language"
+	"llama/modules/language/golang"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCandidateOptions(t *testing.T) {
+	if opts := candidateOptions(nil, 1, 0, 1); opts != nil {
+		t.Errorf("single candidate should keep model defaults, got %+v", opts)
+	}
+
+	seeds := map[int]bool{}
+	temperatures := map[float64]bool{}
+	for i := 0; i < MaxCandidates; i++ {
+		opts := candidateOptions(nil, 2, i, MaxCandidates)
+		seeds[*opts.Seed] = true
+		temperatures[*opts.Temperature] = true
+	}
+	if len(seeds) != MaxCandidates || len(temperatures) != MaxCandidates {
+		t.Errorf("candidates should differ: seeds %v, temperatures %v", seeds, temperatures)
+	}
+	if *candidateOptions(nil, 2, 1, 3).Seed != *candidateOptions(nil, 2, 1, 4).Seed {
+		t.Error("seed should depend only on iteration and index")
+	}
+}
+
+func TestCandidateOptionsKeepJobOptions(t *testing.T) {
+	temperature, seed, numCtx := 0.7, 42, 4096
+	job := &ollamaimplementation.Options{Temperature: &temperature, Seed: &seed, NumCtx: &numCtx}
+
+	for i := 0; i < 3; i++ {
+		opts := candidateOptions(job, 2, i, 3)
+		if *opts.Temperature != 0.7 || *opts.Seed != 42+i || *opts.NumCtx != 4096 {
+			t.Errorf("candidate %d: temperature %v, seed %d, num_ctx %d", i, *opts.Temperature, *opts.Seed, *opts.NumCtx)
+		}
+	}
+	if seed != 42 || temperature != 0.7 {
+		t.Error("job options were modified")
+	}
+
+	// Only the seed is fixed: temperatures still vary
+	job = &ollamaimplementation.Options{Seed: &seed}
+	if first, second := candidateOptions(job, 1, 0, 2), candidateOptions(job, 1, 1, 2); *first.Seed != 42 || *first.Temperature == *second.Temperature {
+		t.Errorf("candidates: %+v, %+v", first, second)
+	}
+}
+
+func TestRunCandidatesKeepJobOptions(t *testing.T) {
+	code := "```go\npackage main\n\nfunc main() {}\n```"
+	scripted := llm.NewScripted(code, code, code)
+	temperature, seed := 0.7, 42
+	job := recoveryJob(t, seedLang{golang.New()})
+	job.LLM, job.Candidates = scripted, 3
+	job.Options = &ollamaimplementation.Options{Temperature: &temperature, Seed: &seed}
+
+	runCandidates(job, &recordingSink{}, 1, promptPlan{Prompt: "add two numbers"})
+
+	seeds := map[int]bool{}
+	for _, req := range scripted.Requests() {
+		if *req.Options.Temperature != 0.7 {
+			t.Errorf("temperature %v, want 0.7", *req.Options.Temperature)
+		}
+		seeds[*req.Options.Seed] = true
+	}
+	if len(seeds) != 3 || !seeds[42] || !seeds[43] || !seeds[44] {
+		t.Errorf("seeds %v, want 42, 43 and 44", seeds)
+	}
+}
+
+func TestNewExecutionJobLimitsCandidates(t *testing.T) {
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Cancel()
+	if job.Candidates != 1 {
+		t.Errorf("Candidates = %d, want default 1", job.Candidates)
+	}
+
+	_, err = newExecutionJob(CompileRequest{Prompt: "add two numbers", Candidates: MaxCandidates + 1})
+	if err == nil {
+		t.Error("expected an error for too many candidates")
+	}
+}
+
+func TestBestCandidate(t *testing.T) {
+	failing := &CompilationResult{ErrorType: ErrorTypeType, CompileErrors: []string{"undefined: x"}}
+	passing := &CompilationResult{Success: true, ErrorType: ErrorTypeSuccess}
+
+	candidates := []*candidate{
+		{Index: 0, AbortCode: "extraction_failed"},
+		{Index: 1, Result: failing, Score: scoreResult(failing)},
+		{Index: 2, Result: passing, Score: scoreResult(passing)},
+		{Index: 3, Result: passing, Score: scoreResult(passing)},
+	}
+	if best := bestCandidate(candidates); best.Index != 2 {
+		t.Errorf("best = %d, want the first passing candidate 2", best.Index)
+	}
+	if best := bestCandidate(candidates[:1]); best != nil {
+		t.Errorf("best = %+v, want nil when nothing compiled", best)
+	}
+}
+
+// seedLang passes code written for seed 101 and fails the rest
+type seedLang struct {
+	language.Language
+}
+
+func (seedLang) Compile(ctx context.Context, mainCode, testCode string) (*CompilationResult, error) {
+	if strings.Contains(mainCode, "seed 101") {
+		return &CompilationResult{Success: true, ErrorType: ErrorTypeSuccess}, nil
+	}
+	return &CompilationResult{ErrorType: ErrorTypeSyntax, CompileErrors: []string{"syntax error"}}, nil
+}
+
+func TestRunCandidates(t *testing.T) {
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Options *ollamaimplementation.Options `json:"options"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		seed := 0
+		if req.Options != nil {
+			seed = *req.Options.Seed
+		}
+		code := fmt.Sprintf("```go\npackage main\n\n// seed %d\nfunc main() {}\n```", seed)
+		json.NewEncoder(w).Encode(chatReply(code))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	job := recoveryJob(t, seedLang{golang.New()})
+	job.Candidates = 3
+	sink := &recordingSink{}
+
+	// Seeds 100, 101, 102: only the second candidate passes
+	candidates := runCandidates(job, sink, 1, promptPlan{Prompt: "add two numbers"})
+	if len(candidates) != 3 {
+		t.Fatalf("got %d candidates, want 3", len(candidates))
+	}
+	if best := bestCandidate(candidates); best == nil || best.Index != 1 {
+		t.Errorf("best = %+v, want candidate 1", best)
+	}
+	if got := len(sink.ofType(WSTypeCandidate)); got != 3 {
+		t.Errorf("sent %d candidate messages, want 3", got)
+	}
+}
+
+func TestRunCandidateReportsLLMErrors(t *testing.T) {
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model 'missing:1b' not found, try pulling it first"}`))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	job := recoveryJob(t, golang.New())
+	job.Model = "missing:1b"
+
+	c := runCandidate(job, &recordingSink{}, 1, 0, promptPlan{Prompt: "add two numbers"}, nil)
+	if c.AbortCode != "model_not_found" || !strings.Contains(c.Err, "ollama pull missing:1b") {
+		t.Errorf("abort %q: %s", c.AbortCode, c.Err)
+	}
+}
+
+// classifyingLang fails like seedLang but calls every failure a runtime error
+type classifyingLang struct {
+	seedLang
+}
+
+func (classifyingLang) ClassifyError(*CompilationResult) ErrorType { return ErrorTypeRuntime }
+
+func TestRunCandidateClassifiesWithBackend(t *testing.T) {
+	code := "```go\npackage main\n\nfunc main() {}\n```"
+
+	job := recoveryJob(t, classifyingLang{seedLang{golang.New()}})
+	job.LLM = llm.NewScripted(code)
+	if c := runCandidate(job, &recordingSink{}, 1, 0, promptPlan{Prompt: "add two numbers"}, nil); c.Result == nil || c.Result.ErrorType != ErrorTypeRuntime {
+		t.Errorf("result = %+v, want the backend's runtime error", c.Result)
+	}
+
+	// The Go backend can't classify a result without a stage, so what
+	// Compile reported stands
+	job = recoveryJob(t, seedLang{golang.New()})
+	job.LLM = llm.NewScripted(code)
+	if c := runCandidate(job, &recordingSink{}, 1, 0, promptPlan{Prompt: "add two numbers"}, nil); c.Result == nil || c.Result.ErrorType != ErrorTypeSyntax {
+		t.Errorf("result = %+v, want Compile's syntax error", c.Result)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// CONFIGURATION FILE
+// ============================================================================
+
+// Config is the server configuration read from the -config file, e.g.
+//
+//	{
+//	  "defaultModel": "llama3.2:1b",
+//	  "defaultProvider": "ollama",
+//	  "providers": {
+//	    "llamacpp": {"type": "openai", "endpoint": "http://localhost:8080"},
+//	    "canned": {"type": "scripted", "responses": ["```go\npackage main\n..."]}
+//	  },
+//	  "systemPrompt": "You are a careful Go programmer.",
+//	  "replayDir": "/var/lib/llama/replays",
+//	  "models": {
+//	    "llama3.2": {"temperature": 0.2, "num_ctx": 8192},
+//	    "llama3.1": {"stop": ["<|eot_id|>"]}
+//	  }
+//	}
+type Config struct {
+	DefaultModel string `json:"defaultModel"`
+
+	// SystemPrompt replaces the built-in system prompt. The language's
+	// format rules are always appended to it.
+	SystemPrompt string `json:"systemPrompt"`
+
+	// Providers are the LLM backends jobs can pick by name, next to the
+	// built-in "ollama" for the local Ollama server
+	DefaultProvider string                `json:"defaultProvider"`
+	Providers       map[string]llm.Config `json:"providers"`
+
+	// Models holds generation defaults by full model name or by family
+	// (the name without its tag). A job's own options override them.
+	Models map[string]ollamaimplementation.Options `json:"models"`
+
+	// ReplayDir is where recorded jobs' bundles are saved and looked up
+	ReplayDir string `json:"replayDir"`
+}
+
+// systemPrompt opens every conversation, ahead of the format rules
+var systemPrompt = "You are an expert programmer. You write complete, correct, compilable code together with tests for it, " +
+	"and you answer with code only, in exactly the format the instructions below ask for."
+
+// providers are the LLM backends in use by name, and defaultProvider the
+// one jobs use unless they ask for another
+var (
+	providers       = map[string]llm.Config{"ollama": {Type: llm.ProviderOllama}}
+	defaultProvider = "ollama"
+)
+
+// modelDefaults are the per-model generation defaults in use
+var modelDefaults = map[string]ollamaimplementation.Options{}
+
+// loadConfig reads and validates a configuration file
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for model, options := range cfg.Models {
+		if err := options.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: model %s: %w", path, model, err)
+		}
+	}
+	for name, provider := range cfg.Providers {
+		if err := provider.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: provider %s: %w", path, name, err)
+		}
+	}
+	if _, ok := cfg.Providers[cfg.DefaultProvider]; cfg.DefaultProvider != "" && cfg.DefaultProvider != "ollama" && !ok {
+		return nil, fmt.Errorf("%s: default provider %s is not configured", path, cfg.DefaultProvider)
+	}
+	return &cfg, nil
+}
+
+// apply makes the configuration the one in use
+func (cfg *Config) apply() {
+	if cfg.DefaultModel != "" {
+		defaultModel = cfg.DefaultModel
+	}
+	if cfg.SystemPrompt != "" {
+		systemPrompt = cfg.SystemPrompt
+	}
+	if cfg.DefaultProvider != "" {
+		defaultProvider = cfg.DefaultProvider
+	}
+	if cfg.ReplayDir != "" {
+		replayDir = cfg.ReplayDir
+	}
+	providers = map[string]llm.Config{"ollama": {Type: llm.ProviderOllama}}
+	for name, provider := range cfg.Providers {
+		providers[name] = provider
+	}
+	modelDefaults = make(map[string]ollamaimplementation.Options, len(cfg.Models))
+	for model, options := range cfg.Models {
+		modelDefaults[model] = options
+	}
+}
+
+// jobOptions returns the options for a job running model: the model's
+// configured defaults with the request's options on top
+func jobOptions(model string, requested *ollamaimplementation.Options) *ollamaimplementation.Options {
+	defaults, ok := modelDefaults[model]
+	if !ok {
+		defaults, ok = modelDefaults[strings.SplitN(model, ":", 2)[0]]
+	}
+	if !ok {
+		return requested.Merge(nil)
+	}
+	return defaults.Merge(requested)
+}
+
+// jobLLM returns a fresh client for the named provider, or the default one
+// if name is empty, so scripted providers start over for every job
+func jobLLM(name string) (string, llm.LLMClient, error) {
+	if name == "" {
+		name = defaultProvider
+	}
+	provider, ok := providers[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown provider %q", name)
+	}
+	client, err := llm.New(provider, DefaultLLMResponseTime)
+	return name, client, err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useConfig applies the configuration in data for the rest of the test
+func useConfig(t *testing.T, data string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousModel, previousPrompt, previousDefaults := defaultModel, systemPrompt, modelDefaults
+	previousProvider, previousProviders, previousReplayDir := defaultProvider, providers, replayDir
+	t.Cleanup(func() {
+		defaultModel, systemPrompt, modelDefaults = previousModel, previousPrompt, previousDefaults
+		defaultProvider, providers, replayDir = previousProvider, previousProviders, previousReplayDir
+	})
+	cfg.apply()
+}
+
+func TestJobOptionsFromConfig(t *testing.T) {
+	useConfig(t, `{
+		"defaultModel": "codellama:7b",
+		"systemPrompt": "You write Go.",
+		"models": {
+			"llama3.2": {"temperature": 0.2, "num_ctx": 8192},
+			"llama3.2:1b": {"temperature": 0.4, "stop": ["<|eot_id|>"]}
+		}
+	}`)
+
+	seed := 42
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Model: "llama3.2:1b", Options: &ollamaimplementation.Options{Seed: &seed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+	if o := job.Options; *o.Temperature != 0.4 || *o.Seed != 42 || o.NumCtx != nil || len(o.Stop) != 1 {
+		t.Errorf("full name defaults not applied: %+v", o)
+	}
+
+	if o := jobOptions("llama3.2:3b", nil); *o.Temperature != 0.2 || *o.NumCtx != 8192 {
+		t.Errorf("family defaults not applied: %+v", o)
+	}
+	if o := jobOptions("qwen2.5-coder", nil); o != nil {
+		t.Errorf("unconfigured model got options %+v", o)
+	}
+	if defaultModel != "codellama:7b" {
+		t.Errorf("defaultModel = %s", defaultModel)
+	}
+	if job.SystemPrompt != "You write Go." {
+		t.Errorf("SystemPrompt = %q", job.SystemPrompt)
+	}
+}
+
+func TestLoadConfigRejectsInvalidOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"models": {"llama3.2": {"temperature": -1}}}`), 0o644)
+	if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), "llama3.2") {
+		t.Errorf("err = %v", err)
+	}
+
+	hot := 3.0
+	if _, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Options: &ollamaimplementation.Options{Temperature: &hot}}); err == nil {
+		t.Error("expected an error for an invalid temperature")
+	}
+}
+
+func TestJobProviderFromConfig(t *testing.T) {
+	useConfig(t, `{
+		"defaultProvider": "canned",
+		"providers": {
+			"canned": {"type": "scripted", "responses": ["hello"]},
+			"llamacpp": {"type": "openai", "endpoint": "http://localhost:8080"}
+		}
+	}`)
+
+	for name, want := range map[string]interface{}{"": &llm.Scripted{}, "llamacpp": &llm.OpenAI{}, "ollama": &llm.Ollama{}} {
+		job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Provider: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer job.Cancel()
+		if reflect.TypeOf(job.LLM) != reflect.TypeOf(want) {
+			t.Errorf("provider %q: got a %T", name, job.LLM)
+		}
+	}
+
+	if _, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Provider: "openrouter"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"defaultProvider": "vllm"}`), 0o644)
+	if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), "vllm") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestJobOptionsSentToOllama(t *testing.T) {
+	var body []byte
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(chatReply("no code"))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	// Temperature 0 and a fixed seed for reproducible runs
+	temperature, seed := 0.0, 7
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Options: &ollamaimplementation.Options{Temperature: &temperature, Seed: &seed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+
+	runCandidate(job, &recordingSink{}, 1, 0, promptPlan{Prompt: "add two numbers"}, job.Options)
+	if !strings.Contains(string(body), `"options":{"temperature":0,"seed":7}`) {
+		t.Errorf("options not sent: %s", body)
+	}
+}
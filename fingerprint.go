@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// ERROR FINGERPRINTS
+// ============================================================================
+
+var (
+	fpLocation = regexp.MustCompile(`^(?:\S+: )?[\w./\\-]+\.\w+:\d+(?::\d+)?:\s*`)
+	fpQuoted   = regexp.MustCompile("\"[^\"]*\"|'[^']*'|`[^`]*`")
+	fpNumber   = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b\d+(?:\.\d+)?\b`)
+	fpToken    = regexp.MustCompile(`[A-Za-z_][\w.*]*`)
+	// "undefined: total", "declared and not used: x"
+	fpTrailingIdent = regexp.MustCompile(`:\s*[A-Za-z_][\w.]*$`)
+)
+
+// fpTemplateWords are the words compilers and test runners build their
+// messages from, e.g. "cannot use _ (variable of type int) as string value".
+// Any other word in a message is a name from the code.
+var fpTemplateWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		after allowed an and any argument arguments as assignment at attribute be before block body but by call
+		callable can candidate cannot case const constant conversion cycle declaration declared defined deleted
+		discards division does else end error expected expecting expression failed few field for from func
+		function got has have implement import imported in incompatible incomplete indentation index initialization
+		instead int invalid is it length line literal match matching member method mismatch mismatched missing
+		module more must named newline nil no non not object of on only operand operation operator or out outer
+		overflows panic parameter pointer range receiver redeclared redefinition reference requested required
+		return returns runtime scope should statement string struct subscriptable such support supported syntax
+		takes than the this to too type types unbalanced undeclared undefined unexpected unindent unsupported
+		untyped use used value values variable variables void want was were with within zero
+		bool byte float32 float64 int8 int16 int32 int64 uint rune double char str list dict tuple
+	`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// normalizeMessage reduces an error message to its template so the same
+// mistake matches across iterations even after the code moved around or
+// was renamed: locations go, quoted text becomes '_', numbers become #, a
+// lone name after the last colon becomes _, and so does every other word
+// that isn't one of fpTemplateWords.
+func normalizeMessage(msg string) string {
+	msg = strings.TrimSpace(strings.SplitN(msg, "\n", 2)[0])
+	msg = fpLocation.ReplaceAllString(msg, "")
+	msg = fpQuoted.ReplaceAllString(msg, "'_'")
+	msg = fpNumber.ReplaceAllString(msg, "#")
+	msg = fpTrailingIdent.ReplaceAllString(msg, ": _")
+	msg = fpToken.ReplaceAllStringFunc(msg, func(tok string) string {
+		if fpTemplateWords[tok] {
+			return tok
+		}
+		return "_"
+	})
+	return msg
+}
+
+// failureMessages lists the individual errors of a result
+func failureMessages(result *CompilationResult) []string {
+	var msgs []string
+	if len(result.Diagnostics) > 0 {
+		for _, d := range result.Diagnostics {
+			msgs = append(msgs, d.Message)
+		}
+		return msgs
+	}
+	msgs = append(msgs, result.CompileErrors...)
+	return append(msgs, result.TestErrors...)
+}
+
+// errorFingerprint identifies a failure by its error type and the set of
+// normalized messages. Two attempts that fail for the same reasons get the
+// same fingerprint regardless of line numbers, names or error order.
+func errorFingerprint(result *CompilationResult) string {
+	set := make(map[string]bool)
+	for _, msg := range failureMessages(result) {
+		set[normalizeMessage(msg)] = true
+	}
+	templates := make([]string, 0, len(set))
+	for t := range set {
+		templates = append(templates, t)
+	}
+	sort.Strings(templates)
+
+	sum := sha1.Sum([]byte(result.ErrorType.String() + "\n" + strings.Join(templates, "\n")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// errorCount is how many things are wrong: failed tests for logic errors,
+// otherwise the number of errors reported
+func errorCount(result *CompilationResult) int {
+	if result.ErrorType == ErrorTypeLogic && result.Tests != nil {
+		return result.Tests.Failed
+	}
+	return len(failureMessages(result))
+}
+
+// ============================================================================
+// STUCK DETECTION
+// ============================================================================
+
+// stuckStatus explains why a job is considered stuck
+type stuckStatus struct {
+	Code   string // Abort reason, e.g. "repeated_error"
+	Detail string // Human readable explanation for the client
+}
+
+// errorStageRank orders error types by how far the code got. Moving to a
+// higher rank is progress even if the error count goes up.
+func errorStageRank(t ErrorType) int {
+	switch t {
+	case ErrorTypeSyntax:
+		return 0
+	case ErrorTypeType:
+		return 1
+	case ErrorTypeRuntime, ErrorTypeLogic:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// detectStuck looks at the failed attempts for signs that more iterations
+// won't help: the same failure over and over, a cycle between a few
+// failures, or a window of attempts that never got further than its first.
+// Infrastructure failures aren't the LLM's doing and are ignored.
+func detectStuck(attempts []Attempt) (stuckStatus, bool) {
+	var history []Attempt
+	for _, a := range attempts {
+		if a.Result != nil && a.Result.ErrorType != ErrorTypeInfrastructure {
+			history = append(history, a)
+		}
+	}
+	n := len(history)
+
+	// Same failure several times in a row
+	if repeats := trailingRepeats(history); repeats >= SameErrorThreshold {
+		return stuckStatus{
+			Code:   "repeated_error",
+			Detail: fmt.Sprintf("the same %s error (fingerprint %s) repeated %d times", history[n-1].Result.ErrorType, history[n-1].Fingerprint, repeats),
+		}, true
+	}
+
+	// A→B→A→B (or A→B→C→A→B→C): the fixes keep undoing each other
+	for period := 2; period <= OscillationMaxPeriod; period++ {
+		if n < 2*period {
+			continue
+		}
+		window := history[n-2*period:]
+		cycle := true
+		distinct := make(map[string]bool)
+		for i := 0; i < period; i++ {
+			distinct[window[i].Fingerprint] = true
+			if window[i].Fingerprint != window[i+period].Fingerprint {
+				cycle = false
+				break
+			}
+		}
+		if cycle && len(distinct) > 1 {
+			var names []string
+			for _, a := range window[:period] {
+				names = append(names, a.Fingerprint)
+			}
+			return stuckStatus{
+				Code:   "oscillating_errors",
+				Detail: fmt.Sprintf("errors cycle between %d states (%s)", period, strings.Join(names, " -> ")),
+			}, true
+		}
+	}
+
+	// No attempt in the window got further than the first one did
+	if n >= NoProgressWindow {
+		window := history[n-NoProgressWindow:]
+		first := window[0]
+		improved := false
+		for _, a := range window[1:] {
+			rank, firstRank := errorStageRank(a.Result.ErrorType), errorStageRank(first.Result.ErrorType)
+			if rank > firstRank || (rank == firstRank && a.ErrorCount < first.ErrorCount) {
+				improved = true
+				break
+			}
+		}
+		if !improved {
+			return stuckStatus{
+				Code:   "no_progress",
+				Detail: fmt.Sprintf("no improvement over %d attempts (still %d %s error(s))", NoProgressWindow, window[len(window)-1].ErrorCount, window[len(window)-1].Result.ErrorType),
+			}, true
+		}
+	}
+
+	return stuckStatus{}, false
+}
+
+// trailingRepeats counts how many of the latest attempts share the last fingerprint
+func trailingRepeats(history []Attempt) int {
+	if len(history) == 0 {
+		return 0
+	}
+	last := history[len(history)-1].Fingerprint
+	count := 0
+	for i := len(history) - 1; i >= 0 && history[i].Fingerprint == last; i-- {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"main.go:6:13: undefined: c", "undefined: _"},
+		{"undefined: totalSum", "undefined: _"},
+		{`"os" imported and not used`, "'_' imported and not used"},
+		{"declared and not used: result_2", "declared and not used: _"},
+		{"got 7, want 10", "got #, want #"},
+		{"panic: runtime error: index out of range [5] with length 3", "panic: runtime error: index out of range [#] with length #"},
+		{"cannot use x (variable of type int) as string value in argument to strings.ToUpper", "cannot use _ (variable of type int) as string value in argument to _"},
+		{"test_add: main.py:5: ZeroDivisionError: division by zero", "_: division by zero"},
+		{"cannot use total (variable of type int) as string value in return statement", "cannot use _ (variable of type int) as string value in return statement"},
+		{"count declared and not used", "_ declared and not used"},
+	}
+	for _, test := range tests {
+		if got := normalizeMessage(test.in); got != test.want {
+			t.Errorf("normalizeMessage(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestErrorFingerprint(t *testing.T) {
+	a := &CompilationResult{ErrorType: ErrorTypeType, Diagnostics: []Diagnostic{
+		{File: "main.go", Line: 4, Message: "undefined: total"},
+		{File: "main.go", Line: 9, Message: `"fmt" imported and not used`},
+	}}
+	// Same mistakes, moved and renamed, reported in a different order
+	b := &CompilationResult{ErrorType: ErrorTypeType, Diagnostics: []Diagnostic{
+		{File: "main.go", Line: 2, Message: `"os" imported and not used`},
+		{File: "main.go", Line: 12, Message: "undefined: sum"},
+	}}
+	c := &CompilationResult{ErrorType: ErrorTypeType, Diagnostics: []Diagnostic{
+		{File: "main.go", Line: 4, Message: "missing return"},
+	}}
+
+	// Only a lowercase variable name differs
+	d := &CompilationResult{ErrorType: ErrorTypeType, Diagnostics: []Diagnostic{
+		{File: "main.go", Line: 7, Message: "cannot use total (variable of type int) as string value in return statement"},
+	}}
+	e := &CompilationResult{ErrorType: ErrorTypeType, Diagnostics: []Diagnostic{
+		{File: "main.go", Line: 8, Message: "cannot use sum (variable of type int) as string value in return statement"},
+	}}
+
+	if errorFingerprint(a) != errorFingerprint(b) || errorFingerprint(d) != errorFingerprint(e) {
+		t.Errorf("equivalent failures got different fingerprints")
+	}
+	if errorFingerprint(a) == errorFingerprint(c) {
+		t.Errorf("different failures got the same fingerprint")
+	}
+	if fp := errorFingerprint(a); len(fp) != 12 {
+		t.Errorf("fingerprint %q should be 12 hex characters", fp)
+	}
+}
+
+func TestErrorCount(t *testing.T) {
+	logic := &CompilationResult{ErrorType: ErrorTypeLogic, Tests: &TestReport{Passed: 3, Failed: 2}, TestErrors: []string{"a"}}
+	if got := errorCount(logic); got != 2 {
+		t.Errorf("errorCount(logic) = %d, want 2", got)
+	}
+	build := &CompilationResult{ErrorType: ErrorTypeType, CompileErrors: []string{"a", "b", "c"}}
+	if got := errorCount(build); got != 3 {
+		t.Errorf("errorCount(build) = %d, want 3", got)
+	}
+}
+
+// attempts builds a failure history from fingerprints, error types and counts
+func attempts(specs ...struct {
+	fp    string
+	et    ErrorType
+	count int
+}) []Attempt {
+	var out []Attempt
+	for i, s := range specs {
+		out = append(out, Attempt{
+			Iteration:   i + 1,
+			Result:      &CompilationResult{ErrorType: s.et},
+			Fingerprint: s.fp,
+			ErrorCount:  s.count,
+		})
+	}
+	return out
+}
+
+type spec = struct {
+	fp    string
+	et    ErrorType
+	count int
+}
+
+func TestDetectStuck(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []Attempt
+		wantCode string
+	}{
+		{
+			name:    "different type errors are progress",
+			history: attempts(spec{"a", ErrorTypeType, 3}, spec{"b", ErrorTypeType, 2}, spec{"c", ErrorTypeType, 1}),
+		},
+		{
+			name:     "same fingerprint three times",
+			history:  attempts(spec{"x", ErrorTypeType, 1}, spec{"a", ErrorTypeType, 1}, spec{"a", ErrorTypeType, 1}, spec{"a", ErrorTypeType, 1}),
+			wantCode: "repeated_error",
+		},
+		{
+			name:     "A-B-A-B",
+			history:  attempts(spec{"a", ErrorTypeType, 1}, spec{"b", ErrorTypeLogic, 1}, spec{"a", ErrorTypeType, 1}, spec{"b", ErrorTypeLogic, 1}),
+			wantCode: "oscillating_errors",
+		},
+		{
+			name: "A-B-C-A-B-C",
+			history: attempts(spec{"a", ErrorTypeType, 1}, spec{"b", ErrorTypeType, 1}, spec{"c", ErrorTypeType, 1},
+				spec{"a", ErrorTypeType, 1}, spec{"b", ErrorTypeType, 1}, spec{"c", ErrorTypeType, 1}),
+			wantCode: "oscillating_errors",
+		},
+		{
+			name: "error count not falling",
+			history: attempts(spec{"a", ErrorTypeType, 2}, spec{"b", ErrorTypeType, 2}, spec{"c", ErrorTypeType, 3},
+				spec{"d", ErrorTypeSyntax, 1}, spec{"e", ErrorTypeType, 4}),
+			wantCode: "no_progress",
+		},
+		{
+			name: "reaching the tests is progress",
+			history: attempts(spec{"a", ErrorTypeType, 1}, spec{"b", ErrorTypeType, 2}, spec{"c", ErrorTypeType, 3},
+				spec{"d", ErrorTypeType, 2}, spec{"e", ErrorTypeLogic, 4}),
+		},
+		{
+			name: "infrastructure failures are ignored",
+			history: append(attempts(spec{"a", ErrorTypeType, 1}, spec{"a", ErrorTypeType, 1}),
+				Attempt{Result: &CompilationResult{ErrorType: ErrorTypeInfrastructure}, Fingerprint: "infra"}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, stuck := detectStuck(test.history)
+			if test.wantCode == "" {
+				if stuck {
+					t.Errorf("unexpectedly stuck: %+v", status)
+				}
+				return
+			}
+			if !stuck || status.Code != test.wantCode || status.Detail == "" {
+				t.Errorf("detectStuck = %+v, %v; want code %s", status, stuck, test.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"llama/modules/language"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// JOB CONSTRUCTION
+// ============================================================================
+
+// newExecutionJob validates a request and builds a pending job with defaults
+// applied. The job's context is cancelled by job.Cancel or when Timeout elapses.
+func newExecutionJob(req CompileRequest) (*ExecutionJob, error) {
+	if req.Replay != "" {
+		return newReplayJob(req.Replay, req.Record)
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+
+	languageName := req.Language
+	if strings.TrimSpace(languageName) == "" {
+		languageName = "go"
+	}
+	lang, err := language.Lookup(languageName)
+	if err != nil {
+		return nil, err
+	}
+
+	model := req.Model
+	if model == "" {
+		model = defaultModel
+	}
+
+	system := req.SystemPrompt
+	if strings.TrimSpace(system) == "" {
+		system = systemPrompt
+	}
+
+	maxIterations := req.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+
+	candidates := req.Candidates
+	if candidates <= 0 {
+		candidates = 1
+	}
+	if candidates > MaxCandidates {
+		return nil, fmt.Errorf("at most %d candidates per iteration are supported", MaxCandidates)
+	}
+
+	if err := req.Options.Validate(); err != nil {
+		return nil, err
+	}
+
+	provider, client, err := jobLLM(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	job := &ExecutionJob{
+		ID:            newJobID(),
+		Language:      lang.Name(),
+		Lang:          lang,
+		UserPrompt:    req.Prompt,
+		SystemPrompt:  system,
+		Model:         model,
+		MaxIterations: maxIterations,
+		Candidates:    candidates,
+		PatchMode:     req.PatchMode,
+		Options:       jobOptions(model, req.Options),
+		Provider:      provider,
+		LLM:           client,
+		Timeout:       timeout,
+		Ctx:           ctx,
+		Cancel:        cancel,
+		Status:        "pending",
+	}
+	if req.Record {
+		job.Record = true
+		job.Recorder = llm.NewRecorder(client)
+		job.LLM = job.Recorder
+	}
+	return job, nil
+}
+
+// newJobID returns a random 16 character hex identifier
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	if len(counts) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(counts))
+	for k, v := range counts {
+		out[k] = v
+	}
+	return out
+}
+
+// ============================================================================
+// JOB STATE
+// ============================================================================
+
+// start marks the job as running. Called from the pipeline goroutine.
+func (job *ExecutionJob) start() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Status = "running"
+	job.StartTime = time.Now()
+}
+
+// finish records the job's terminal status and abort reason
+func (job *ExecutionJob) finish(status, abortReason string) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Status = status
+	job.AbortReason = abortReason
+	job.EndTime = time.Now()
+}
+
+func (job *ExecutionJob) setIteration(iteration int) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Metrics.IterationCount = iteration
+}
+
+// recordError updates the error stats with a failed result
+func (job *ExecutionJob) recordError(result *CompilationResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Metrics.LastErrorType = result.ErrorType
+	if result.ErrorSubtype != "" {
+		if job.Metrics.ErrorSubtypeCounts == nil {
+			job.Metrics.ErrorSubtypeCounts = make(map[string]int)
+		}
+		job.Metrics.ErrorSubtypeCounts[result.ErrorSubtype]++
+	}
+}
+
+// recordAutoFixes counts the rewrites made to an iteration's code
+func (job *ExecutionJob) recordAutoFixes(fixes []AutoFix) {
+	if len(fixes) == 0 {
+		return
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.Metrics.AutoFixCounts == nil {
+		job.Metrics.AutoFixCounts = make(map[string]int)
+	}
+	for _, fix := range fixes {
+		job.Metrics.AutoFixCounts[fix.Name]++
+	}
+}
+
+// setMessages replaces the conversation carried into the next iteration
+func (job *ExecutionJob) setMessages(messages []ollamaimplementation.Message) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.LLMCtx.Messages = messages
+}
+
+// conversation returns the conversation the next iteration continues,
+// system prompt first. The caller must hold job.mu.
+func (job *ExecutionJob) conversation() []ollamaimplementation.Message {
+	messages := make([]ollamaimplementation.Message, 0, len(job.LLMCtx.Messages)+1)
+	messages = append(messages, ollamaimplementation.Message{Role: ollamaimplementation.RoleSystem, Content: systemMessage(job)})
+	return append(messages, job.LLMCtx.Messages...)
+}
+
+// recordPatch counts how an iteration's code was produced in patch mode
+func (job *ExecutionJob) recordPatch(c *candidate) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if c.Patch != "" {
+		job.Metrics.PatchesApplied++
+	}
+	if c.PatchError != "" {
+		job.Metrics.PatchFallbacks++
+	}
+}
+
+// recordSolution keeps an iteration's code as the job's best if it scores
+// higher than every earlier one. Ties go to the earlier iteration. A
+// Solution is replaced, never modified, so it can be shared once recorded.
+func (job *ExecutionJob) recordSolution(iteration int, c *candidate) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.Best != nil && !c.Score.Better(job.Best.Score) {
+		return
+	}
+	job.Best = &Solution{
+		Iteration: iteration,
+		MainCode:  c.MainCode,
+		TestCode:  c.TestCode,
+		ErrorType: c.Result.ErrorType.String(),
+		Score:     c.Score,
+	}
+}
+
+// bestSolution returns the job's best solution, or nil
+func (job *ExecutionJob) bestSolution() *Solution {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	return job.Best
+}
+
+func (job *ExecutionJob) setFinalResult(result *CompilationResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.FinalResult = result
+}
+
+// isFinished reports whether the pipeline has stopped working on the job
+func (job *ExecutionJob) isFinished() bool {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	return !job.EndTime.IsZero()
+}
+
+// jobStatusData is the Data payload of JobStatusResponse
+type jobStatusData struct {
+	Language       string                         `json:"language"`
+	Model          string                         `json:"model"`
+	Provider       string                         `json:"provider"`
+	MaxIterations  int                            `json:"maxIterations"`
+	AbortReason    string                         `json:"abortReason,omitempty"`
+	ErrorSubtypes  map[string]int                 `json:"errorSubtypes,omitempty"`
+	AutoFixes      map[string]int                 `json:"autoFixes,omitempty"`
+	PatchMode      bool                           `json:"patchMode,omitempty"`
+	Options        *ollamaimplementation.Options  `json:"options,omitempty"`
+	PatchesApplied int                            `json:"patchesApplied,omitempty"`
+	PatchFallbacks int                            `json:"patchFallbacks,omitempty"`
+	Best           *Solution                      `json:"best,omitempty"`
+	Record         bool                           `json:"record,omitempty"`
+	ReplayOf       string                         `json:"replayOf,omitempty"`    // ID of the recorded job being replayed
+	ReplayDiffs    []string                       `json:"replayDiffs,omitempty"` // How the replay differed from the recording
+	Conversation   []ollamaimplementation.Message `json:"conversation"`
+	Messages       []WSMessage                    `json:"messages"`
+}
+
+// snapshot returns the job's current state for the job API
+func (job *ExecutionJob) snapshot() JobStatusResponse {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+
+	var replayOf string
+	if job.Replay != nil {
+		replayOf = job.Replay.JobID
+	}
+
+	resp := JobStatusResponse{
+		JobID:     job.ID,
+		Status:    job.Status,
+		Iteration: job.Metrics.IterationCount,
+		Data: jobStatusData{
+			Language:       job.Language,
+			Model:          job.Model,
+			Provider:       job.Provider,
+			MaxIterations:  job.MaxIterations,
+			AbortReason:    job.AbortReason,
+			ErrorSubtypes:  copyCounts(job.Metrics.ErrorSubtypeCounts),
+			AutoFixes:      copyCounts(job.Metrics.AutoFixCounts),
+			PatchMode:      job.PatchMode,
+			Options:        job.Options,
+			PatchesApplied: job.Metrics.PatchesApplied,
+			PatchFallbacks: job.Metrics.PatchFallbacks,
+			Best:           job.Best,
+			Record:         job.Record,
+			ReplayOf:       replayOf,
+			ReplayDiffs:    append([]string(nil), job.replayDiffs...),
+			Conversation:   job.conversation(),
+			Messages:       append([]WSMessage{}, job.messages...),
+		},
+	}
+
+	if !job.StartTime.IsZero() {
+		resp.StartTime = job.StartTime.Unix()
+		end := job.EndTime
+		if end.IsZero() {
+			end = time.Now()
+		}
+		resp.ElapsedSeconds = int(end.Sub(job.StartTime).Seconds())
+	}
+
+	return resp
+}
+
+// jobSink records the messages on the job, so the job API can report them,
+// before forwarding them to next. Tokens are only forwarded: the iteration
+// messages carry the whole response. next may be nil for jobs without a
+// listener.
+type jobSink struct {
+	job  *ExecutionJob
+	next MessageSink
+}
+
+func newJobSink(job *ExecutionJob, next MessageSink) *jobSink {
+	return &jobSink{job: job, next: next}
+}
+
+func (s *jobSink) Send(msg WSMessage) error {
+	if msg.Type != WSTypeToken {
+		s.job.mu.Lock()
+		s.job.messages = append(s.job.messages, msg)
+		s.job.mu.Unlock()
+	}
+
+	if s.next == nil {
+		return nil
+	}
+	return s.next.Send(msg)
+}
+
+// ============================================================================
+// JOB REGISTRY
+// ============================================================================
+
+// jobRetention is how long finished jobs stay queryable
+const jobRetention = time.Hour
+
+// JobRegistry is an in-memory, concurrency-safe index of jobs by ID
+type JobRegistry struct {
+	mu   sync.RWMutex
+	jobs map[string]*ExecutionJob
+}
+
+func NewJobRegistry() *JobRegistry {
+	return &JobRegistry{jobs: make(map[string]*ExecutionJob)}
+}
+
+var jobRegistry = NewJobRegistry()
+
+// Add registers a job and drops finished jobs older than jobRetention
+func (r *JobRegistry) Add(job *ExecutionJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, old := range r.jobs {
+		old.mu.RLock()
+		expired := !old.EndTime.IsZero() && time.Since(old.EndTime) > jobRetention
+		old.mu.RUnlock()
+		if expired {
+			delete(r.jobs, id)
+		}
+	}
+
+	r.jobs[job.ID] = job
+}
+
+// Get returns the job with the given ID
+func (r *JobRegistry) Get(id string) (*ExecutionJob, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	job, ok := r.jobs[id]
+	return job, ok
+}
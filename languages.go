@@ -0,0 +1,8 @@
+package main
+
+// Language backends register themselves with the language registry on import
+import (
+	_ "llama/modules/language/cpp"
+	_ "llama/modules/language/golang"
+	_ "llama/modules/language/python"
+)
@@ -2,17 +2,14 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"llama/modules/compiler_v2/go_compiler_v2"
-	displayindicator "llama/modules/display-indicator"
-	"llama/modules/extraction"
-	ollamaimplementation "llama/modules/ollama-implementation"
+	"os"
+	"strings"
 
 	// "log"
 	"net/http"
 	"path/filepath"
-	"strings"
-	"time"
 
 	"github.com/gorilla/websocket"
 	// "go.mongodb.org/mongo-driver/bson"
@@ -20,7 +17,7 @@ import (
 	// "go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var model string = "llama3.2:1b"
+var defaultModel = "llama3.2:1b"
 
 // var model string = "codellama:13b"
 // var model string = "codellama"
@@ -55,18 +52,6 @@ var model string = "llama3.2:1b"
 //     }
 // }
 
-type compilerFunc func(string, string) ([]byte, error)
-
-type ResponseData struct {
-	Iteration            uint   `json:"iteration"`
-	GeneratedCode        string `json:"generated_code"`
-	GeneratedCode1       string `json:"generatedCode1"` // Add this
-	GeneratedCode2       string `json:"generatedCode2"` // Add this
-	CompilerOutput       string `json:"compiler_output"`
-	CompiledSuccessfully bool   `json:"compiled_successfully"`
-	TotalExecutionTime   string `json:"total_execution_time,omitempty"`
-}
-
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -74,60 +59,50 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func removeUnwantedLines(input string) string {
-	lines := strings.Split(input, "\n")
-	var result []string
-	for _, line := range lines {
-		// Skip lines starting with "go: " or "# command-line-arguments"
-		if strings.HasPrefix(line, "go: ") || strings.HasPrefix(line, "# command-line-arguments") {
-			continue
-		}
-		result = append(result, line)
-	}
-	return strings.Join(result, "\n")
-}
+func main() {
+	configPath := flag.String("config", "", "JSON file with the default model and per-model generation options")
+	replayPath := flag.String("replay", "", "Replay bundle to rerun without a model, reporting how it differs from the recording")
+	flag.Parse()
 
-// Function to remove lines containing "go mod tidy" anywhere in the line
-func removeGoModTidyLines(input string) string {
-	lines := strings.Split(input, "\n")
-	var result []string
-	for _, line := range lines {
-		if strings.Contains(line, "go mod tidy") {
-			continue
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
 		}
-		result = append(result, line)
+		cfg.apply()
 	}
-	return strings.Join(result, "\n")
-}
-
-func removeLinesContaining(input string) string {
-	keyword := "tempOutput"
-	lines := strings.Split(input, "\n")
-	var result []string
 
-	for _, line := range lines {
-		if !strings.Contains(line, keyword) {
-			result = append(result, line)
-		}
+	if *replayPath != "" {
+		os.Exit(runReplay(*replayPath))
 	}
 
-	return strings.Join(result, "\n")
-}
-
-func main() {
 	// initMongoDB()  // Initialize MongoDB connection
-	http.HandleFunc("/", serveIndex)
-	http.HandleFunc("/ws", handleWebSocket) // WebSocket route
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
-
 	fmt.Println("Starting server on http://localhost:8080")
-	http.ListenAndServe(":8080", nil)
+	http.ListenAndServe(":8080", newMux())
+}
+
+// newMux registers the server's routes
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveIndex)
+	mux.HandleFunc("/ws", handleWebSocket) // WebSocket route
+	mux.HandleFunc("/api/compile", handleCompile)
+	mux.HandleFunc("/api/jobs/", handleJob)
+	mux.HandleFunc("/api/models", handleModels)
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
+	return mux
 }
 
 func serveIndex(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filepath.Join("templates", "index.html"))
 }
 
+// handleWebSocket runs compilation jobs for a single browser connection.
+// The client sends a "start" message to launch a job and may send "cancel"
+// to stop it. Only one job runs per connection at a time, and dropping the
+// connection cancels whatever is still running. A "pull" message downloads
+// a model alongside, reporting its progress.
 func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -136,125 +111,104 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	sink := newWSSink(conn)
 
-	var msg map[string]string
-	if err := conn.ReadJSON(&msg); err != nil {
-		// Normal close codes (1000, 1001) are expected when client disconnects
-		if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
-			fmt.Printf("WebSocket error: %v\n", err)
+	var job *ExecutionJob
+	var jobDone chan struct{}
+	defer func() {
+		if job != nil {
+			job.Cancel()
+			<-jobDone
 		}
-		return
-	}
-	userPrompt := msg["prompt"]
-
-	// Update model based on user selection
-	if selectedModel, ok := msg["model"]; ok {
-		model = selectedModel
-		fmt.Println("Model updated to:", model)
-	}
+	}()
 
-	go RunProgram(ctx, conn, userPrompt, extraction.GoPrompt, go_compiler_v2.NewGoCompiler().CheckCompileErrors)
+	// Downloads stop with the connection
+	pulls, stopPulls := context.WithCancel(context.Background())
+	defer stopPulls()
 
-	// Keep connection open and listen for client disconnect or cancellation messages
 	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			// Normal close - client disconnected
+		var msg WSClientMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			// Normal close codes (1000, 1001) are expected when client disconnects
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
 				fmt.Printf("WebSocket closed: %v\n", err)
 			}
-			cancel()
-			break
+			return
 		}
-	}
-}
-
-func RunProgram(ctx context.Context, conn *websocket.Conn, userPrompt string, languagePrompt string, compiler compilerFunc) {
-	currentConversationContext := []int{}
-	var numOfIterations uint = 1
-	startTime := time.Now() // Track start time for execution duration
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("Process canceled by client")
-			return // Exit the loop if context is canceled
-		default:
-			// Prepare the prompt for LLM
-			modifiedPrompt := userPrompt + languagePrompt
-			fmt.Println("Prompt received. Generating response...")
-
-			// Display loading indicator (for testing purposes)
-			done := make(chan bool)
-			go displayindicator.DisplayLoadingIndicator(done)
 
-			// Generate LLM response
-			fmt.Println("Model:", model)
-			response, updatedContext, err := ollamaimplementation.GetOllamaResponse(modifiedPrompt, currentConversationContext, model)
-			done <- true
-
-			if err != nil {
-				fmt.Println("Error generating response:", err)
-				conn.WriteJSON(ResponseData{CompilerOutput: "Error generating response"})
-				return
+		running := false
+		if jobDone != nil {
+			select {
+			case <-jobDone:
+			default:
+				running = true
 			}
+		}
 
-			fmt.Println("LLM Response", response)
-			currentConversationContext = updatedContext
-
-			// Use new extraction with fallback strategy
-			extractor := extraction.NewExtractor()
-			generatedCode1, generatedCode2, strategyUsed, errExtract := extractor.Extract(response)
-
-			// If extraction fails, use fallback
-			if errExtract != nil {
-				fmt.Printf("Extraction failed with error: %v. Using fallback strategy...\n", errExtract)
-				generatedCode1, generatedCode2, strategyUsed = extractor.ExtractWithFallback(response)
+		switch msg.Type {
+		case WSClientCancel:
+			if !running {
+				sendErrorMessage(sink, "No job is running")
+				continue
 			}
+			fmt.Printf("[Job %s] Cancel requested by client\n", job.ID)
+			job.Cancel()
 
-			fmt.Printf("Extraction successful using strategy: %s\n", strategyUsed)
-			fmt.Println("Main code extracted:", len(generatedCode1), "bytes")
-			fmt.Println("Test code extracted:", len(generatedCode2), "bytes")
-
-			// Handle empty code extraction
-			if len(generatedCode1) == 0 {
-				fmt.Println("Improper LLM response: main code is empty")
-				conn.WriteJSON(ResponseData{CompilerOutput: "Improper LLM response: main code is empty"})
+		case WSClientStart, "":
+			if running {
+				sendErrorMessage(sink, "A job is already running on this connection")
 				continue
 			}
 
-			// Compile the generated code
-			output, err := compiler(generatedCode1, generatedCode2)
-			fmt.Println(err)
-			compilationSuccess := err == nil
-
-			// Prepare response data with generatedCode1 and generatedCode2
-			responseData := ResponseData{
-				Iteration:            numOfIterations,
-				GeneratedCode:        response,
-				GeneratedCode1:       generatedCode1, // Include here
-				GeneratedCode2:       generatedCode2, // Include here
-				CompilerOutput:       removeLinesContaining(removeGoModTidyLines(removeUnwantedLines(string(output)))),
-				CompiledSuccessfully: compilationSuccess,
-				TotalExecutionTime:   time.Since(startTime).String(),
+			newJob, err := newExecutionJob(CompileRequest{
+				Language:      msg.Language,
+				Prompt:        msg.Prompt,
+				Model:         msg.Model,
+				MaxIterations: msg.MaxIterations,
+				Candidates:    msg.Candidates,
+				PatchMode:     msg.PatchMode,
+				Options:       msg.Options,
+				SystemPrompt:  msg.SystemPrompt,
+				Provider:      msg.Provider,
+				Record:        msg.Record,
+				Replay:        msg.Replay,
+				Timeout:       msg.Timeout,
+			})
+			if err != nil {
+				sendErrorMessage(sink, err.Error())
+				continue
+			}
+			if err := checkModelInstalled(newJob); err != nil {
+				newJob.Cancel()
+				sendErrorMessage(sink, err.Error())
+				continue
 			}
 
-			// Insert the generated code into MongoDB
-			// insertGeneratedCode(responseData)
-
-			// Send iteration data back to the client
-			conn.WriteJSON(responseData)
-
-			// Check for successful compilation
-			if compilationSuccess {
-				return
+			fmt.Printf("[Job %s] Started (language=%s, model=%s, provider=%s)\n", newJob.ID, newJob.Language, newJob.Model, newJob.Provider)
+			job = newJob
+			jobDone = make(chan struct{})
+			jobRegistry.Add(job)
+			go func(job *ExecutionJob, done chan struct{}) {
+				defer close(done)
+				RunCompilationJob(job, newJobSink(job, sink))
+			}(job, jobDone)
+
+		case WSClientPull:
+			if strings.TrimSpace(msg.Model) == "" {
+				sendErrorMessage(sink, "model is required")
+				continue
 			}
+			go pullModel(pulls, sink, msg.Provider, strings.TrimSpace(msg.Model))
 
-			// Update prompt with errors for next iteration
-			userPrompt = "Fix the errors in the code. Keep the test cases. Format as two code blocks (main and test). Error:\n" + (removeLinesContaining(removeGoModTidyLines(removeUnwantedLines(string(output)))))
-			numOfIterations++
+		default:
+			sendErrorMessage(sink, fmt.Sprintf("Unknown message type: %s", msg.Type))
 		}
 	}
 }
+
+func sendErrorMessage(sink MessageSink, message string) {
+	sink.Send(WSMessage{
+		Type: WSTypeError,
+		Data: WSErrorData{Message: message},
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialTestServer(t *testing.T) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(newMux())
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func readTestMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	var msg map[string]interface{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocketRejectsInvalidStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     WSClientMessage
+		wantErr string
+	}{
+		{"empty prompt", WSClientMessage{Type: WSClientStart, Language: "go"}, "prompt is required"},
+		{"unknown language", WSClientMessage{Type: WSClientStart, Language: "cobol", Prompt: "add two numbers"}, "unsupported language"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := dialTestServer(t)
+			if err := conn.WriteJSON(test.msg); err != nil {
+				t.Fatalf("Failed to send message: %v", err)
+			}
+
+			msg := readTestMessage(t, conn)
+			if msg["type"] != string(WSTypeError) {
+				t.Fatalf("Expected error message, got %v", msg)
+			}
+			data := msg["data"].(map[string]interface{})
+			if !strings.Contains(data["message"].(string), test.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", test.wantErr, data["message"])
+			}
+		})
+	}
+}
+
+func TestWebSocketCancelWithoutJob(t *testing.T) {
+	conn := dialTestServer(t)
+	if err := conn.WriteJSON(WSClientMessage{Type: WSClientCancel}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	msg := readTestMessage(t, conn)
+	if msg["type"] != string(WSTypeError) {
+		t.Errorf("Expected error message, got %v", msg)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// MODEL DISCOVERY AND DOWNLOADS
+// ============================================================================
+
+// modelListTimeout bounds a request for the installed models, which Ollama
+// answers from disk
+const modelListTimeout = 5 * time.Second
+
+// ollamaProvider returns the Ollama client of the named provider, or of the
+// built-in "ollama" provider if name is empty
+func ollamaProvider(name string) (*ollamaimplementation.Client, error) {
+	if name == "" {
+		name = "ollama"
+	}
+	_, client, err := jobLLM(name)
+	if err != nil {
+		return nil, err
+	}
+	ollama, ok := client.(*llm.Ollama)
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not an Ollama server", name)
+	}
+	return ollama.Client, nil
+}
+
+// modelInstalled reports whether name is among models. A name without a tag
+// means the "latest" tag, as it does to Ollama.
+func modelInstalled(models []ollamaimplementation.ModelInfo, name string) bool {
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	for _, m := range models {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkModelInstalled rejects a job whose Ollama server is up but doesn't
+// have the job's model. Jobs on other providers, or on a server that can't
+// be asked, are let through to fail on their first request if they must.
+func checkModelInstalled(job *ExecutionJob) error {
+	ollama, ok := llm.Base(job.LLM).(*llm.Ollama)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(job.Ctx, modelListTimeout)
+	defer cancel()
+	models, err := ollama.Client.ListModels(ctx)
+	if err != nil {
+		fmt.Printf("[Job %s] Could not list installed models, not checking %s: %v\n", job.ID, job.Model, err)
+		return nil
+	}
+	if modelInstalled(models, job.Model) {
+		return nil
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	sort.Strings(names)
+	return fmt.Errorf("model %s is not installed (installed: %s); pull it first", job.Model, strings.Join(names, ", "))
+}
+
+// handleModels lists the models installed on an Ollama provider
+// (GET /api/models[?provider=name]), "ollama" by default
+func handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	client, err := ollamaProvider(r.URL.Query().Get("provider"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), modelListTimeout)
+	defer cancel()
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, ollamaimplementation.ErrServerUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		writeAPIError(w, status, err.Error())
+		return
+	}
+
+	resp := ModelsResponse{Models: make([]ModelSummary, 0, len(models))}
+	for _, m := range models {
+		resp.Models = append(resp.Models, ModelSummary{
+			Name:          m.Name,
+			Size:          m.Size,
+			Family:        m.Details.Family,
+			ParameterSize: m.Details.ParameterSize,
+			Quantization:  m.Details.QuantizationLevel,
+		})
+	}
+	sort.Slice(resp.Models, func(i, j int) bool { return resp.Models[i].Name < resp.Models[j].Name })
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// pullModel downloads a model on an Ollama provider, streaming its progress
+// to sink and finishing with a message that has Done set
+func pullModel(ctx context.Context, sink MessageSink, provider, model string) {
+	send := func(data WSPullData) {
+		data.Model = model
+		sink.Send(WSMessage{Type: WSTypePull, Data: data})
+	}
+
+	client, err := ollamaProvider(provider)
+	if err != nil {
+		send(WSPullData{Done: true, Error: err.Error()})
+		return
+	}
+
+	fmt.Printf("Pulling model %s\n", model)
+	err = client.Pull(ctx, model, func(p ollamaimplementation.PullProgress) {
+		send(WSPullData{Status: p.Status, Digest: p.Digest, Total: p.Total, Completed: p.Completed})
+	})
+	if err != nil {
+		fmt.Printf("Pulling model %s failed: %v\n", model, err)
+		send(WSPullData{Done: true, Error: err.Error()})
+		return
+	}
+	fmt.Printf("Pulled model %s\n", model)
+	send(WSPullData{Status: "success", Done: true})
+}
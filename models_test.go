@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// installedModels serves a tags endpoint listing names
+func installedModels(t *testing.T, names ...string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tags struct {
+			Models []ollamaimplementation.ModelInfo `json:"models"`
+		}
+		for _, name := range names {
+			tags.Models = append(tags.Models, ollamaimplementation.ModelInfo{
+				Name:    name,
+				Size:    1 << 30,
+				Details: ollamaimplementation.ModelDetails{Family: "llama", ParameterSize: "1.2B"},
+			})
+		}
+		json.NewEncoder(w).Encode(tags)
+	}))
+	t.Cleanup(server.Close)
+
+	original := ollamaimplementation.OllamaTagsEndpoint
+	ollamaimplementation.OllamaTagsEndpoint = server.URL
+	t.Cleanup(func() { ollamaimplementation.OllamaTagsEndpoint = original })
+}
+
+func TestModelInstalled(t *testing.T) {
+	models := []ollamaimplementation.ModelInfo{{Name: "llama3.2:1b"}, {Name: "codellama:latest"}}
+	tests := map[string]bool{
+		"llama3.2:1b":      true,
+		"codellama":        true,
+		"codellama:latest": true,
+		"llama3.2":         false,
+		"llama3.2:3b":      false,
+	}
+	for name, want := range tests {
+		if got := modelInstalled(models, name); got != want {
+			t.Errorf("modelInstalled(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestModelsAPI(t *testing.T) {
+	installedModels(t, "qwen2.5-coder:7b", "llama3.2:1b")
+	server := httptest.NewServer(newMux())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var models ModelsResponse
+	json.NewDecoder(resp.Body).Decode(&models)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(models.Models) != 2 {
+		t.Fatalf("status %d, models %+v", resp.StatusCode, models)
+	}
+	if m := models.Models[0]; m.Name != "llama3.2:1b" || m.Size != 1<<30 || m.Family != "llama" || m.ParameterSize != "1.2B" {
+		t.Errorf("first model = %+v", m)
+	}
+
+	resp, err = http.Get(server.URL + "/api/models?provider=openrouter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown provider: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestModelsAPIServerDown(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	original := ollamaimplementation.OllamaTagsEndpoint
+	ollamaimplementation.OllamaTagsEndpoint = down.URL
+	defer func() { ollamaimplementation.OllamaTagsEndpoint = original }()
+
+	server := httptest.NewServer(newMux())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/api/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestStartRejectsMissingModel(t *testing.T) {
+	installedModels(t, "llama3.2:1b")
+
+	conn := dialTestServer(t)
+	conn.WriteJSON(WSClientMessage{Type: WSClientStart, Prompt: "add two numbers", Model: "codellama:7b"})
+	msg := readTestMessage(t, conn)
+	if msg["type"] != string(WSTypeError) {
+		t.Fatalf("Expected error message, got %v", msg)
+	}
+	message := msg["data"].(map[string]interface{})["message"].(string)
+	if !strings.Contains(message, "codellama:7b is not installed") || !strings.Contains(message, "llama3.2:1b") {
+		t.Errorf("message = %q", message)
+	}
+
+	server := httptest.NewServer(newMux())
+	defer server.Close()
+	if resp, _ := postCompile(t, server, CompileRequest{Prompt: "add two numbers", Model: "codellama:7b"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("API: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestWebSocketPull(t *testing.T) {
+	pullServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaimplementation.PullRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "llama3.2:1b" || !req.Stream {
+			t.Errorf("pull request = %+v", req)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.Encode(ollamaimplementation.PullProgress{Status: "pulling manifest"})
+		encoder.Encode(ollamaimplementation.PullProgress{Status: "pulling dde5aa3fc5ff", Total: 100, Completed: 50})
+		encoder.Encode(ollamaimplementation.PullProgress{Status: "success"})
+	}))
+	defer pullServer.Close()
+	original := ollamaimplementation.OllamaPullEndpoint
+	ollamaimplementation.OllamaPullEndpoint = pullServer.URL
+	defer func() { ollamaimplementation.OllamaPullEndpoint = original }()
+
+	conn := dialTestServer(t)
+	conn.WriteJSON(WSClientMessage{Type: WSClientPull, Model: "llama3.2:1b"})
+
+	var progress []WSPullData
+	for len(progress) == 0 || !progress[len(progress)-1].Done {
+		var msg struct {
+			Type WSMessageType `json:"type"`
+			Data WSPullData    `json:"data"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != WSTypePull {
+			t.Fatalf("unexpected %s message", msg.Type)
+		}
+		progress = append(progress, msg.Data)
+	}
+
+	last := progress[len(progress)-1]
+	if last.Error != "" || last.Model != "llama3.2:1b" || last.Status != "success" {
+		t.Errorf("last message = %+v", last)
+	}
+	if len(progress) != 4 || progress[1].Completed != 50 || progress[1].Total != 100 {
+		t.Errorf("progress = %+v", progress)
+	}
+}
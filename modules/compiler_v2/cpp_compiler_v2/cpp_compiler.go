@@ -0,0 +1,362 @@
+package cpp_compiler_v2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// C++ COMPILER WITH GCC/CLANG DIAGNOSTIC PARSING
+// ============================================================================
+
+const (
+	mainFileName   = "main.cpp"
+	testFileName   = "test.cpp"
+	runnerFileName = "test_runner.cpp"
+)
+
+type CppCompilerV2 struct{}
+
+func NewCppCompilerV2() *CppCompilerV2 {
+	return &CppCompilerV2{}
+}
+
+// CompilationResultV2 holds detailed compilation output
+type CompilationResultV2 struct {
+	Success       bool
+	ExitCode      int
+	Compiler      string // g++ or clang++ binary used
+	CompileOutput string
+	TestOutput    string
+	RawOutput     string
+	ErrorType     ErrorTypeCpp
+	ExecutionTime time.Duration
+	CompileErrors []string
+	TestErrors    []string
+}
+
+// ErrorTypeCpp classifies C++ compilation and execution errors
+type ErrorTypeCpp int
+
+const (
+	ErrorTypeCppUnknown        ErrorTypeCpp = iota
+	ErrorTypeCppInfrastructure              // No compiler, workspace issues, build timeout
+	ErrorTypeCppSyntax                      // Parse errors
+	ErrorTypeCppType                        // Undeclared names, bad conversions, link errors
+	ErrorTypeCppLogic                       // Failed asserts, non-zero test exit
+	ErrorTypeCppRuntime                     // SIGSEGV, uncaught exceptions, hangs
+	ErrorTypeCppSuccess                     // No error
+)
+
+// Compile builds main.cpp on its own to check it compiles and links, then
+// builds and runs a test binary. The test binary is a single translation
+// unit that includes main.cpp with its main renamed, followed by the tests,
+// so test code sees every function without needing a header. If the tests
+// don't define main, one is generated that calls every void test*() function.
+func (cc *CppCompilerV2) Compile(ctx context.Context, mainCode, testCode string) (*CompilationResultV2, error) {
+	startTime := time.Now()
+	result := &CompilationResultV2{}
+	defer func() { result.ExecutionTime = time.Since(startTime) }()
+
+	compiler, err := findCompiler()
+	if err != nil {
+		return infrastructureResult(result, err.Error()), nil
+	}
+	result.Compiler = compiler
+
+	// Create fresh temp directory
+	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("cpp_compile_%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return infrastructureResult(result, fmt.Sprintf("Failed to create temp directory: %v", err)), nil
+	}
+	defer os.RemoveAll(tempDir)
+
+	testCode = stripMainInclude(testCode)
+	hasTests := strings.TrimSpace(testCode) != ""
+
+	files := map[string]string{mainFileName: mainCode}
+	if hasTests {
+		files[testFileName] = testCode
+		files[runnerFileName] = buildTestRunner(testCode)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			return infrastructureResult(result, fmt.Sprintf("Failed to write %s: %v", name, err)), nil
+		}
+	}
+
+	// Stage 1: build the program itself
+	programBinary := executableName("program")
+	output, exitCode, _, err := run(ctx, tempDir, compiler, "-std=c++17", "-Wall", "-o", programBinary, mainFileName)
+	result.CompileOutput = output
+	result.RawOutput = output
+	if ctx.Err() != nil {
+		return infrastructureResult(result, "Compilation exceeded timeout"), nil
+	}
+	if err != nil && exitCode < 0 {
+		return infrastructureResult(result, fmt.Sprintf("Failed to run %s: %v", compiler, err)), nil
+	}
+	if exitCode != 0 {
+		return buildFailure(result, output, exitCode), nil
+	}
+
+	if !hasTests {
+		result.Success = true
+		result.ErrorType = ErrorTypeCppSuccess
+		return result, nil
+	}
+
+	// Stage 2: build the test binary
+	testBinary := executableName("test_runner")
+	output, exitCode, _, err = run(ctx, tempDir, compiler, "-std=c++17", "-Wall", "-o", testBinary, runnerFileName)
+	result.CompileOutput += output
+	result.RawOutput = result.CompileOutput
+	if ctx.Err() != nil {
+		return infrastructureResult(result, "Compilation exceeded timeout"), nil
+	}
+	if err != nil && exitCode < 0 {
+		return infrastructureResult(result, fmt.Sprintf("Failed to run %s: %v", compiler, err)), nil
+	}
+	if exitCode != 0 {
+		return buildFailure(result, output, exitCode), nil
+	}
+
+	// Stage 3: run the tests
+	output, exitCode, signal, _ := run(ctx, tempDir, filepath.Join(tempDir, testBinary))
+	result.TestOutput = output
+	result.RawOutput = result.CompileOutput + "\n" + output
+	if ctx.Err() != nil {
+		result.ExitCode = 1
+		result.ErrorType = ErrorTypeCppRuntime
+		result.TestErrors = []string{"Tests exceeded timeout (possible infinite loop)"}
+		return result, nil
+	}
+	if exitCode == 0 && signal == "" {
+		result.Success = true
+		result.ErrorType = ErrorTypeCppSuccess
+		return result, nil
+	}
+
+	result.ExitCode = exitCode
+	if result.ExitCode == 0 {
+		result.ExitCode = 1
+	}
+	result.TestErrors = parseRunErrors(output, signal)
+	result.ErrorType = classifyRunError(output, signal)
+	return result, nil
+}
+
+func infrastructureResult(result *CompilationResultV2, message string) *CompilationResultV2 {
+	result.ErrorType = ErrorTypeCppInfrastructure
+	result.ExitCode = 1
+	result.RawOutput = strings.TrimSpace(result.RawOutput + "\n" + message)
+	result.CompileErrors = append(result.CompileErrors, message)
+	return result
+}
+
+func buildFailure(result *CompilationResultV2, output string, exitCode int) *CompilationResultV2 {
+	result.ExitCode = exitCode
+	result.CompileErrors = parseDiagnostics(output)
+	result.ErrorType = classifyBuildError(result.CompileErrors)
+	return result
+}
+
+// findCompiler honours $CXX and otherwise uses g++ or clang++, whichever is on PATH
+func findCompiler() (string, error) {
+	candidates := []string{"g++", "clang++"}
+	if cxx := os.Getenv("CXX"); cxx != "" {
+		candidates = append([]string{cxx}, candidates...)
+	}
+	for _, name := range candidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no C++ compiler found on PATH (tried g++ and clang++)")
+}
+
+func executableName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// run executes a command in dir and returns its combined output, exit code
+// and, if it was killed by one, the signal description (e.g. "segmentation
+// fault"). The exit code is -1 if the process could not be started.
+func run(ctx context.Context, dir, name string, args ...string) (string, int, string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err == nil {
+		return output.String(), 0, "", nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		state := exitErr.ProcessState.String()
+		if strings.HasPrefix(state, "signal: ") {
+			return output.String(), 1, strings.TrimPrefix(state, "signal: "), err
+		}
+		return output.String(), exitErr.ExitCode(), "", err
+	}
+	return output.String(), -1, "", err
+}
+
+// ============================================================================
+// TEST RUNNER GENERATION
+// ============================================================================
+
+var (
+	mainIncludePattern  = regexp.MustCompile(`(?m)^\s*#\s*include\s*"main\.(cpp|h|hpp)"\s*$`)
+	mainFuncPattern     = regexp.MustCompile(`(?m)^\s*int\s+main\s*\(`)
+	testFunctionPattern = regexp.MustCompile(`(?m)^\s*(?:static\s+)?void\s+([Tt]est\w*)\s*\(\s*(?:void)?\s*\)\s*\{`)
+)
+
+// stripMainInclude removes #include "main.cpp" (or main.h) lines, since the
+// runner already includes main.cpp
+func stripMainInclude(testCode string) string {
+	return mainIncludePattern.ReplaceAllString(testCode, "")
+}
+
+func buildTestRunner(testCode string) string {
+	var runner strings.Builder
+	runner.WriteString("// Generated test runner\n")
+	runner.WriteString("#define main program_main\n")
+	runner.WriteString("#include \"" + mainFileName + "\"\n")
+	runner.WriteString("#undef main\n")
+	runner.WriteString("#include \"" + testFileName + "\"\n")
+
+	if mainFuncPattern.MatchString(testCode) {
+		return runner.String()
+	}
+
+	runner.WriteString("\n#include <iostream>\n\nint main() {\n")
+	for _, match := range testFunctionPattern.FindAllStringSubmatch(testCode, -1) {
+		runner.WriteString(fmt.Sprintf("    %s();\n    std::cout << \"%s passed\" << std::endl;\n", match[1], match[1]))
+	}
+	runner.WriteString("    return 0;\n}\n")
+	return runner.String()
+}
+
+// ============================================================================
+// ERROR PARSING & CLASSIFICATION
+// ============================================================================
+
+var (
+	// main.cpp:5:12: error: 'x' was not declared in this scope
+	diagnosticPattern = regexp.MustCompile(`^(.+?):(\d+):(\d+): (?:fatal error|error): (.*)$`)
+	// main.cpp:(.text+0x1f): undefined reference to `foo()'  /  ld: undefined symbol
+	linkerPattern = regexp.MustCompile(`undefined reference to|undefined symbol|multiple definition of|ld returned`)
+	// test.cpp:6: void test_add(): Assertion `add(2, 3) == 6' failed.
+	assertionPattern = regexp.MustCompile(`(?:^|\s)([\w./]+):(\d+): .*Assertion .* failed\.?$`)
+	syntaxPattern    = regexp.MustCompile(`expected|before|stray|missing terminating|unterminated|unbalanced|extraneous`)
+)
+
+// parseDiagnostics extracts "file:line:col: error: message" diagnostics and
+// linker errors from compiler output. Paths are reduced to the file name.
+func parseDiagnostics(output string) []string {
+	var errors []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := diagnosticPattern.FindStringSubmatch(line); m != nil {
+			errors = append(errors, fmt.Sprintf("%s:%s:%s: error: %s", filepath.Base(m[1]), m[2], m[3], m[4]))
+			continue
+		}
+		if linkerPattern.MatchString(line) && !strings.HasPrefix(line, "collect2") {
+			errors = append(errors, line)
+		}
+	}
+	if len(errors) == 0 && strings.TrimSpace(output) != "" {
+		errors = append(errors, strings.TrimSpace(output))
+	}
+	return errors
+}
+
+// classifyBuildError treats a build as a syntax error if any diagnostic is a
+// parse error, and as a type error (undeclared names, bad conversions, link
+// failures) otherwise
+func classifyBuildError(diagnostics []string) ErrorTypeCpp {
+	for _, diagnostic := range diagnostics {
+		m := diagnosticPattern.FindStringSubmatch(diagnostic)
+		message := diagnostic
+		if m != nil {
+			message = m[4]
+		}
+		if syntaxPattern.MatchString(message) {
+			return ErrorTypeCppSyntax
+		}
+	}
+	if len(diagnostics) > 0 {
+		return ErrorTypeCppType
+	}
+	return ErrorTypeCppUnknown
+}
+
+func parseRunErrors(output, signal string) []string {
+	var errors []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := assertionPattern.FindStringSubmatch(line); m != nil {
+			errors = append(errors, fmt.Sprintf("%s:%s: %s", filepath.Base(m[1]), m[2], line[strings.Index(line, "Assertion"):]))
+			continue
+		}
+		if strings.HasPrefix(line, "terminate called") || strings.HasPrefix(line, "libc++abi") || strings.HasPrefix(line, "what():") {
+			errors = append(errors, line)
+		}
+	}
+	if signal != "" {
+		errors = append(errors, "test binary killed by signal: "+signal)
+	}
+	if len(errors) == 0 {
+		errors = append(errors, "tests exited with a non-zero status")
+	}
+	return errors
+}
+
+// classifyRunError treats a failed assert (which aborts) or a non-zero exit
+// as a logic error and any other crash as a runtime error
+func classifyRunError(output, signal string) ErrorTypeCpp {
+	if strings.Contains(output, "Assertion") && strings.Contains(output, "failed") {
+		return ErrorTypeCppLogic
+	}
+	if signal != "" || strings.Contains(output, "terminate called") {
+		return ErrorTypeCppRuntime
+	}
+	return ErrorTypeCppLogic
+}
+
+// ClassifyCppError classifies a failed compilation from its parsed compile
+// errors, or from the test output when the build succeeded
+func ClassifyCppError(compileErrors []string, output string) ErrorTypeCpp {
+	if len(compileErrors) > 0 {
+		return classifyBuildError(compileErrors)
+	}
+	signal := ""
+	if idx := strings.Index(output, "signal: "); idx >= 0 {
+		signal = strings.TrimSpace(strings.SplitN(output[idx+len("signal: "):], "\n", 2)[0])
+	}
+	return classifyRunError(output, signal)
+}
+
+// Version reports the compiler in use, the first line of its --version
+func Version(ctx context.Context) (string, error) {
+	cxx, err := findCompiler()
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.CommandContext(ctx, cxx, "--version").Output()
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), err
+}
@@ -0,0 +1,133 @@
+package cpp_compiler_v2
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const addMain = `#include <iostream>
+
+int add(int a, int b) {
+    return a + b;
+}
+
+int main() {
+    std::cout << add(2, 3) << std::endl;
+    return 0;
+}
+`
+
+func TestCompile(t *testing.T) {
+	if _, err := findCompiler(); err != nil {
+		t.Skip("no C++ compiler installed")
+	}
+
+	tests := []struct {
+		name          string
+		mainCode      string
+		testCode      string
+		wantSuccess   bool
+		wantErrorType ErrorTypeCpp
+	}{
+		{
+			name:     "tests pass with generated main",
+			mainCode: addMain,
+			testCode: `#include <cassert>
+
+void test_add() {
+    assert(add(2, 3) == 5);
+}
+`,
+			wantSuccess:   true,
+			wantErrorType: ErrorTypeCppSuccess,
+		},
+		{
+			name:     "tests pass with own main and main.cpp include",
+			mainCode: addMain,
+			testCode: `#include <cassert>
+#include "main.cpp"
+
+int main() {
+    assert(add(1, 1) == 2);
+    return 0;
+}
+`,
+			wantSuccess:   true,
+			wantErrorType: ErrorTypeCppSuccess,
+		},
+		{
+			name:          "syntax error",
+			mainCode:      "int add(int a, int b) {\n    return a + b\n}\n\nint main() { return 0; }\n",
+			wantErrorType: ErrorTypeCppSyntax,
+		},
+		{
+			name:          "undeclared identifier",
+			mainCode:      "int add(int a, int b) {\n    return a + c;\n}\n\nint main() { return 0; }\n",
+			wantErrorType: ErrorTypeCppType,
+		},
+		{
+			name:     "failed assert",
+			mainCode: addMain,
+			testCode: `#include <cassert>
+
+void test_add() {
+    assert(add(2, 3) == 6);
+}
+`,
+			wantErrorType: ErrorTypeCppLogic,
+		},
+		{
+			name:     "segmentation fault",
+			mainCode: "int deref(int *p) {\n    return *p;\n}\n\nint main() { return 0; }\n",
+			testCode: `void test_deref() {
+    volatile int *p = nullptr;
+    *p = deref(nullptr);
+}
+`,
+			wantErrorType: ErrorTypeCppRuntime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			result, err := NewCppCompilerV2().Compile(ctx, test.mainCode, test.testCode)
+			if err != nil {
+				t.Fatalf("Compile returned error: %v", err)
+			}
+			if result.Success != test.wantSuccess || result.ErrorType != test.wantErrorType {
+				t.Errorf("got success=%v errorType=%v, want success=%v errorType=%v\noutput:\n%s",
+					result.Success, result.ErrorType, test.wantSuccess, test.wantErrorType, result.RawOutput)
+			}
+		})
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `/tmp/cpp_compile_1/main.cpp: In function 'int add(int, int)':
+/tmp/cpp_compile_1/main.cpp:2:16: error: 'c' was not declared in this scope
+    2 |     return a + c;
+      |                ^
+`
+	errors := parseDiagnostics(output)
+	want := "main.cpp:2:16: error: 'c' was not declared in this scope"
+	if len(errors) != 1 || errors[0] != want {
+		t.Errorf("parseDiagnostics = %q, want [%q]", errors, want)
+	}
+	if got := classifyBuildError(errors); got != ErrorTypeCppType {
+		t.Errorf("classifyBuildError = %v, want %v", got, ErrorTypeCppType)
+	}
+}
+
+func TestBuildTestRunner(t *testing.T) {
+	runner := buildTestRunner("void test_a() {}\nvoid testB(void) {\n}\n")
+	for _, want := range []string{"#define main program_main", "test_a();", "testB();", "int main()"} {
+		if !strings.Contains(runner, want) {
+			t.Errorf("runner missing %q:\n%s", want, runner)
+		}
+	}
+}
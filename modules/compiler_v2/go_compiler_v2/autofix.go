@@ -0,0 +1,460 @@
+package go_compiler_v2
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// AUTO-FIXES
+// ============================================================================
+
+// Names of the rewrites AutoFix makes
+const (
+	FixAddPackageClause      = "add_package_clause"
+	FixRenamePackage         = "rename_package"
+	FixRemoveDuplicateMain   = "remove_duplicate_main"
+	FixRemoveDuplicateDecl   = "remove_duplicate_declaration"
+	FixRemoveDuplicateImport = "remove_duplicate_import"
+	FixRemoveUnusedImport    = "remove_unused_import"
+	FixAddMissingImport      = "add_missing_import"
+	FixRemoveUnusedVariable  = "remove_unused_variable"
+)
+
+// Fix is one rewrite made by AutoFix
+type Fix struct {
+	Name   string // One of the Fix* constants
+	File   string // "main.go" or "main_test.go"
+	Detail string // What was rewritten, e.g. `"os"` for an import
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Name, f.File, f.Detail)
+}
+
+// stdlibImports maps the package names generated code uses to their import
+// paths, for adding imports the LLM forgot
+var stdlibImports = map[string]string{
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"context":  "context",
+	"errors":   "errors",
+	"fmt":      "fmt",
+	"io":       "io",
+	"maps":     "maps",
+	"math":     "math",
+	"os":       "os",
+	"reflect":  "reflect",
+	"regexp":   "regexp",
+	"slices":   "slices",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"testing":  "testing",
+	"time":     "time",
+	"unicode":  "unicode",
+	"atomic":   "sync/atomic",
+	"big":      "math/big",
+	"bits":     "math/bits",
+	"filepath": "path/filepath",
+	"heap":     "container/heap",
+	"json":     "encoding/json",
+	"list":     "container/list",
+	"rand":     "math/rand",
+	"utf8":     "unicode/utf8",
+}
+
+// AutoFix rewrites mechanical mistakes small models keep making before the
+// code is compiled: a missing or wrong package clause, declarations the test
+// file repeats from the main file (main in particular), unused variables,
+// and duplicate, unused or missing imports. Every fix is deterministic and
+// only made where it can't change what the code means; a file that doesn't
+// parse is left alone apart from its package clause. Files without fixes are
+// returned unchanged, the others gofmt'ed.
+func AutoFix(mainCode, testCode string) (string, string, []Fix) {
+	files := []*fixFile{{name: "main.go", src: mainCode}}
+	hasTests := strings.TrimSpace(testCode) != ""
+	if hasTests {
+		files = append(files, &fixFile{name: "main_test.go", src: testCode})
+	}
+
+	var fixes []Fix
+	for _, f := range files {
+		fixes = append(fixes, fixPackageClause(f)...)
+	}
+	if hasTests {
+		fixes = append(fixes, removeDuplicateDecls(files[0], files[1])...)
+	}
+	for _, f := range files {
+		fixes = append(fixes, removeUnusedVariables(f)...)
+	}
+	// Imports last, since removing code can leave one unused
+	for _, f := range files {
+		fixes = append(fixes, fixImports(f, files)...)
+	}
+
+	for _, f := range files {
+		if !f.changed {
+			continue
+		}
+		if formatted, err := format.Source([]byte(f.src)); err == nil {
+			f.src = string(formatted)
+		}
+	}
+
+	if !hasTests {
+		return files[0].src, testCode, fixes
+	}
+	return files[0].src, files[1].src, fixes
+}
+
+// fixFile is a source file being rewritten
+type fixFile struct {
+	name    string
+	src     string
+	changed bool
+}
+
+// edit replaces src[start:end] with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+// parse returns the file's syntax tree, or nil if it doesn't parse
+func (f *fixFile) parse(fset *token.FileSet) *ast.File {
+	file, err := parser.ParseFile(fset, f.name, f.src, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	return file
+}
+
+// apply makes edits, which must not overlap
+func (f *fixFile) apply(edits []edit) {
+	if len(edits) == 0 {
+		return
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		f.src = f.src[:e.start] + e.text + f.src[e.end:]
+	}
+	f.changed = true
+}
+
+// fixPackageClause makes sure the file starts with "package main"
+func fixPackageClause(f *fixFile) []Fix {
+	fset := token.NewFileSet()
+	file := fset.AddFile(f.name, -1, len(f.src))
+	var s scanner.Scanner
+	s.Init(file, []byte(f.src), nil, 0)
+
+	if _, tok, _ := s.Scan(); tok != token.PACKAGE {
+		f.src = "package main\n\n" + f.src
+		f.changed = true
+		return []Fix{{Name: FixAddPackageClause, File: f.name, Detail: "package main"}}
+	}
+
+	pos, tok, name := s.Scan()
+	if tok != token.IDENT || name == "main" {
+		return nil
+	}
+	start := file.Offset(pos)
+	f.apply([]edit{{start, start + len(name), "main"}})
+	return []Fix{{Name: FixRenamePackage, File: f.name, Detail: name + " -> main"}}
+}
+
+// removeDuplicateDecls drops declarations from the test file that the main
+// file already has. LLMs often repeat the code under test, or main, in the
+// test block.
+func removeDuplicateDecls(main, test *fixFile) []Fix {
+	fset := token.NewFileSet()
+	mainFile, testFile := main.parse(fset), test.parse(fset)
+	if mainFile == nil || testFile == nil {
+		return nil
+	}
+
+	declared := make(map[string]bool)
+	for _, d := range mainFile.Decls {
+		for _, key := range declKeys(d) {
+			declared[key] = true
+		}
+	}
+
+	var edits []edit
+	var fixes []Fix
+	for _, d := range testFile.Decls {
+		keys := declKeys(d)
+		if len(keys) == 0 {
+			continue
+		}
+		duplicate := true
+		for _, key := range keys {
+			duplicate = duplicate && declared[key]
+		}
+		if !duplicate {
+			continue
+		}
+
+		start := d.Pos()
+		if doc := declDoc(d); doc != nil {
+			start = doc.Pos()
+		}
+		edits = append(edits, edit{fset.Position(start).Offset, fset.Position(d.End()).Offset, ""})
+
+		name := FixRemoveDuplicateDecl
+		if keys[0] == "main" {
+			name = FixRemoveDuplicateMain
+		}
+		fixes = append(fixes, Fix{Name: name, File: test.name, Detail: strings.Join(keys, ", ")})
+	}
+
+	test.apply(edits)
+	return fixes
+}
+
+// declKeys names what a top-level declaration declares; methods are keyed
+// by receiver type. Imports declare nothing here.
+func declKeys(d ast.Decl) []string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return []string{receiverName(d.Recv.List[0].Type) + "." + d.Name.Name}
+		}
+		return []string{d.Name.Name}
+	case *ast.GenDecl:
+		var keys []string
+		for _, spec := range d.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				keys = append(keys, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					if name.Name != "_" {
+						keys = append(keys, name.Name)
+					}
+				}
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+func declDoc(d ast.Decl) *ast.CommentGroup {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// receiverName returns the type name of a method receiver, e.g. Stack for *Stack[T]
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// removeUnusedVariables renames local variables that are declared with :=
+// and never mentioned again to _, and deletes unused "var x T" statements
+func removeUnusedVariables(f *fixFile) []Fix {
+	fset := token.NewFileSet()
+	file := f.parse(fset)
+	if file == nil {
+		return nil
+	}
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	// Every variable is mentioned once where it is declared
+	mentions := make(map[*ast.Object]int)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Obj != nil && id.Obj.Kind == ast.Var {
+			mentions[id.Obj]++
+		}
+		return true
+	})
+	unused := func(id *ast.Ident, decl ast.Node) bool {
+		return id.Name != "_" && id.Obj != nil && id.Obj.Decl == decl && mentions[id.Obj] == 1
+	}
+
+	var edits []edit
+	var fixes []Fix
+	typeSwitches := make(map[ast.Stmt]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.TypeSwitchStmt:
+			// "switch v := x.(type)" declares v per case, not here
+			typeSwitches[s.Assign] = true
+
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE || typeSwitches[s] {
+				return true
+			}
+			kept, renamed := 0, 0
+			for _, lhs := range s.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok || id.Name == "_" {
+					continue
+				}
+				if !unused(id, s) {
+					kept++
+					continue
+				}
+				edits = append(edits, edit{offset(id.Pos()), offset(id.End()), "_"})
+				fixes = append(fixes, Fix{Name: FixRemoveUnusedVariable, File: f.name, Detail: id.Name})
+				renamed++
+			}
+			// "_ := f()" declares nothing
+			if renamed > 0 && kept == 0 {
+				edits = append(edits, edit{offset(s.TokPos), offset(s.TokPos) + len(":="), "="})
+			}
+
+		case *ast.DeclStmt:
+			gen, ok := s.Decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR || len(gen.Specs) != 1 {
+				return true
+			}
+			spec := gen.Specs[0].(*ast.ValueSpec)
+			if len(spec.Names) != 1 || len(spec.Values) != 0 || !unused(spec.Names[0], spec) {
+				return true
+			}
+			edits = append(edits, edit{offset(s.Pos()), offset(s.End()), ""})
+			fixes = append(fixes, Fix{Name: FixRemoveUnusedVariable, File: f.name, Detail: spec.Names[0].Name})
+		}
+		return true
+	})
+
+	f.apply(edits)
+	return fixes
+}
+
+// fixImports drops duplicate imports and unused standard library imports,
+// and adds the standard library packages the code uses without importing
+// them. Names declared at the top level of any file are never imported.
+func fixImports(f *fixFile, files []*fixFile) []Fix {
+	fset := token.NewFileSet()
+	file := f.parse(fset)
+	if file == nil {
+		return nil
+	}
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	declared := make(map[string]bool)
+	for _, other := range files {
+		if parsed := other.parse(token.NewFileSet()); parsed != nil {
+			for name := range parsed.Scope.Objects {
+				declared[name] = true
+			}
+		}
+	}
+
+	// Package references are selectors on names that resolve to nothing
+	// in the file
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	var fixes []Fix
+	var specs []string
+	seen := make(map[string]bool)
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil
+		}
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		key := name + " " + path
+		if seen[key] {
+			fixes = append(fixes, Fix{Name: FixRemoveDuplicateImport, File: f.name, Detail: spec.Path.Value})
+			continue
+		}
+		seen[key] = true
+
+		pkg := name
+		if pkg == "" {
+			pkg = path[strings.LastIndex(path, "/")+1:]
+		}
+		// Only standard library paths are known to be named after their
+		// last element
+		stdlib := !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+		if stdlib && pkg != "_" && pkg != "." && !used[pkg] {
+			fixes = append(fixes, Fix{Name: FixRemoveUnusedImport, File: f.name, Detail: spec.Path.Value})
+			continue
+		}
+
+		imported[pkg] = true
+		specs = append(specs, f.src[offset(spec.Pos()):offset(spec.End())])
+	}
+
+	var missing []string
+	for name := range used {
+		if path, ok := stdlibImports[name]; ok && !imported[name] && !declared[name] {
+			missing = append(missing, path)
+		}
+	}
+	sort.Strings(missing)
+	for _, path := range missing {
+		fixes = append(fixes, Fix{Name: FixAddMissingImport, File: f.name, Detail: strconv.Quote(path)})
+		specs = append(specs, strconv.Quote(path))
+	}
+
+	if len(fixes) == 0 {
+		return nil
+	}
+
+	// Replace every import declaration with a single one
+	var block string
+	if len(specs) > 0 {
+		block = "import (\n\t" + strings.Join(specs, "\n\t") + "\n)"
+	}
+
+	var first, last ast.Decl
+	for _, d := range file.Decls {
+		if gen, ok := d.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if first == nil {
+				first = d
+			}
+			last = d
+		}
+	}
+	if first == nil {
+		at := offset(file.Name.End())
+		f.apply([]edit{{at, at, "\n\n" + block}})
+	} else {
+		f.apply([]edit{{offset(first.Pos()), offset(last.End()), block}})
+	}
+	return fixes
+}
@@ -0,0 +1,163 @@
+package go_compiler_v2
+
+import (
+	"strings"
+	"testing"
+)
+
+// fixNames lists the names of the fixes as "name:detail"
+func fixNames(fixes []Fix) string {
+	var names []string
+	for _, f := range fixes {
+		names = append(names, f.Name+":"+f.Detail)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestAutoFixPackageClause(t *testing.T) {
+	mainCode, testCode, fixes := AutoFix("func main() {}\n", "package solution\n\nimport \"testing\"\n\nfunc TestX(t *testing.T) {}\n")
+
+	if !strings.HasPrefix(mainCode, "package main\n") || !strings.HasPrefix(testCode, "package main\n") {
+		t.Errorf("package clauses not fixed:\n%s\n%s", mainCode, testCode)
+	}
+	if got := fixNames(fixes); got != "add_package_clause:package main rename_package:solution -> main" {
+		t.Errorf("fixes = %s", got)
+	}
+}
+
+func TestAutoFixDuplicateDeclarations(t *testing.T) {
+	mainCode := `package main
+
+func Add(a, b int) int { return a + b }
+
+func main() {}
+`
+	testCode := `package main
+
+import "testing"
+
+// Add adds
+func Add(a, b int) int { return a + b }
+
+func main() {}
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fail()
+	}
+}
+`
+	_, fixed, fixes := AutoFix(mainCode, testCode)
+
+	if strings.Contains(fixed, "func Add") || strings.Contains(fixed, "func main") || strings.Contains(fixed, "// Add adds") {
+		t.Errorf("duplicates left in the test file:\n%s", fixed)
+	}
+	if !strings.Contains(fixed, "func TestAdd") {
+		t.Errorf("test removed:\n%s", fixed)
+	}
+	if got := fixNames(fixes); got != "remove_duplicate_declaration:Add remove_duplicate_main:main" {
+		t.Errorf("fixes = %s", got)
+	}
+}
+
+func TestAutoFixImports(t *testing.T) {
+	mainCode := `package main
+
+import (
+	"fmt"
+	"os"
+	"fmt"
+	"github.com/example/unused"
+)
+
+func Join(parts []string) string {
+	return strings.Join(parts, ",")
+}
+
+func main() {
+	fmt.Println(Join([]string{"a"}))
+}
+`
+	fixed, _, fixes := AutoFix(mainCode, "")
+
+	want := `package main
+
+import (
+	"fmt"
+	"github.com/example/unused"
+	"strings"
+)
+`
+	if !strings.HasPrefix(fixed, want) {
+		t.Errorf("imports =\n%s\nwant prefix\n%s", fixed, want)
+	}
+	if got := fixNames(fixes); got != `remove_unused_import:"os" remove_duplicate_import:"fmt" add_missing_import:"strings"` {
+		t.Errorf("fixes = %s", got)
+	}
+}
+
+func TestAutoFixAddsImportDeclaration(t *testing.T) {
+	testCode := "package main\n\nfunc TestX(t *testing.T) {}\n"
+	_, fixed, fixes := AutoFix("package main\n\nfunc main() {}\n", testCode)
+
+	if !strings.Contains(fixed, "import (\n\t\"testing\"\n)") {
+		t.Errorf("testing not imported:\n%s", fixed)
+	}
+	if len(fixes) != 1 || fixes[0].File != "main_test.go" {
+		t.Errorf("fixes = %v", fixes)
+	}
+}
+
+func TestAutoFixUnusedVariables(t *testing.T) {
+	mainCode := `package main
+
+import "strconv"
+
+func Parse(s string) int {
+	n, err := strconv.Atoi(s)
+	unused := n * 2
+	var spare int
+	if v := n; v > 0 {
+		return n
+	}
+	switch x := interface{}(s).(type) {
+	case string:
+	}
+	return 0
+}
+
+func main() {}
+`
+	fixed, _, fixes := AutoFix(mainCode, "")
+
+	for _, want := range []string{"n, _ := strconv.Atoi(s)", "_ = n * 2"} {
+		if !strings.Contains(fixed, want) {
+			t.Errorf("missing %q in:\n%s", want, fixed)
+		}
+	}
+	if strings.Contains(fixed, "var spare") {
+		t.Errorf("unused var declaration kept:\n%s", fixed)
+	}
+	if !strings.Contains(fixed, "if v := n; v > 0") || !strings.Contains(fixed, "switch x := ") {
+		t.Errorf("used or type switch variables touched:\n%s", fixed)
+	}
+	if got := fixNames(fixes); got != "remove_unused_variable:err remove_unused_variable:unused remove_unused_variable:spare" {
+		t.Errorf("fixes = %s", got)
+	}
+}
+
+func TestAutoFixLeavesCorrectCodeAlone(t *testing.T) {
+	mainCode := "package main\n\nimport \"fmt\"\n\nfunc main() {\n    fmt.Println(1)\n}\n"
+	fixed, _, fixes := AutoFix(mainCode, "")
+	if fixed != mainCode || len(fixes) != 0 {
+		t.Errorf("correct code changed (%v):\n%s", fixes, fixed)
+	}
+}
+
+func TestAutoFixSkipsUnparsableFiles(t *testing.T) {
+	mainCode := "package main\n\nimport \"os\"\n\nfunc main() {\n"
+	fixed, _, fixes := AutoFix(mainCode, "")
+	if fixed != mainCode || len(fixes) != 0 {
+		t.Errorf("unparsable code changed (%v):\n%s", fixes, fixed)
+	}
+}
@@ -0,0 +1,133 @@
+package go_compiler_v2
+
+import (
+	"strings"
+)
+
+// ============================================================================
+// PHASE-AWARE ERROR CLASSIFICATION
+// ============================================================================
+
+// Stage is a step of the Go build-and-test pipeline
+type Stage string
+
+const (
+	StageNone        Stage = ""
+	StageModule      Stage = "module"       // go mod init / go mod tidy
+	StageBuild       Stage = "build"        // go build of the main package
+	StageVet         Stage = "vet"          // go vet checks
+	StageTestCompile Stage = "test_compile" // building the test binary
+	StageTest        Stage = "test"         // running the tests
+)
+
+// ErrorSubtypeGo is a finer-grained error category, used for stats and to
+// pick the repair hint sent to the LLM
+type ErrorSubtypeGo string
+
+const (
+	SubtypeNone             ErrorSubtypeGo = ""
+	SubtypeMissingModule    ErrorSubtypeGo = "missing_module"
+	SubtypeSyntax           ErrorSubtypeGo = "syntax"
+	SubtypeUnusedImport     ErrorSubtypeGo = "unused_import"
+	SubtypeUnusedVariable   ErrorSubtypeGo = "unused_variable"
+	SubtypeUndefined        ErrorSubtypeGo = "undefined"
+	SubtypeMissingReturn    ErrorSubtypeGo = "missing_return"
+	SubtypeTypeMismatch     ErrorSubtypeGo = "type_mismatch"
+	SubtypeArgumentCount    ErrorSubtypeGo = "argument_count"
+	SubtypeRedeclared       ErrorSubtypeGo = "redeclared"
+	SubtypeUnknownPackage   ErrorSubtypeGo = "unknown_package"
+	SubtypeVet              ErrorSubtypeGo = "vet"
+	SubtypeAssertionFailure ErrorSubtypeGo = "assertion_failure"
+	SubtypePanic            ErrorSubtypeGo = "panic"
+	SubtypeTimeout          ErrorSubtypeGo = "timeout"
+	SubtypeDeadlock         ErrorSubtypeGo = "deadlock"
+	SubtypeOther            ErrorSubtypeGo = "other"
+)
+
+// subtypePatterns maps compiler message fragments to subtypes, checked in order
+var subtypePatterns = []struct {
+	pattern string
+	subtype ErrorSubtypeGo
+}{
+	{"syntax error", SubtypeSyntax},
+	{"imported and not used", SubtypeUnusedImport},
+	{"declared and not used", SubtypeUnusedVariable},
+	{"undefined:", SubtypeUndefined},
+	{"undeclared name", SubtypeUndefined},
+	{"missing return", SubtypeMissingReturn},
+	{"not enough arguments", SubtypeArgumentCount},
+	{"too many arguments", SubtypeArgumentCount},
+	{"not enough return values", SubtypeArgumentCount},
+	{"too many return values", SubtypeArgumentCount},
+	{"redeclared in this block", SubtypeRedeclared},
+	{"already declared", SubtypeRedeclared},
+	{"is not in std", SubtypeUnknownPackage},
+	{"no required module provides package", SubtypeUnknownPackage},
+	{"cannot use", SubtypeTypeMismatch},
+	{"mismatched types", SubtypeTypeMismatch},
+	{"invalid operation", SubtypeTypeMismatch},
+	{"cannot convert", SubtypeTypeMismatch},
+}
+
+// DiagnosticSubtype categorises a single diagnostic
+func DiagnosticSubtype(d Diagnostic) ErrorSubtypeGo {
+	switch d.Phase {
+	case PhaseVet:
+		return SubtypeVet
+	case PhaseTest:
+		switch {
+		case strings.HasPrefix(d.Message, "panic: test timed out"):
+			return SubtypeTimeout
+		case strings.HasPrefix(d.Message, "fatal error: all goroutines are asleep"):
+			return SubtypeDeadlock
+		case strings.HasPrefix(d.Message, "panic: "), strings.HasPrefix(d.Message, "fatal error: "):
+			return SubtypePanic
+		default:
+			return SubtypeAssertionFailure
+		}
+	}
+
+	msg := strings.ToLower(d.Message)
+	for _, p := range subtypePatterns {
+		if strings.Contains(msg, p.pattern) {
+			return p.subtype
+		}
+	}
+	return SubtypeOther
+}
+
+// ClassifyGoError derives the error class from the stage that failed and its
+// diagnostics. The first diagnostic is taken as the primary one, since later
+// compiler errors are frequently consequences of it, except that any syntax
+// error wins because nothing else is reliable until the file parses.
+func ClassifyGoError(stage Stage, diags []Diagnostic) (ErrorTypeGo, ErrorSubtypeGo) {
+	switch stage {
+	case StageModule:
+		return ErrorTypeGoInfrastructure, SubtypeMissingModule
+
+	case StageBuild, StageTestCompile:
+		if len(diags) == 0 {
+			return ErrorTypeGoType, SubtypeOther
+		}
+		for _, d := range diags {
+			if DiagnosticSubtype(d) == SubtypeSyntax {
+				return ErrorTypeGoSyntax, SubtypeSyntax
+			}
+		}
+		return ErrorTypeGoType, DiagnosticSubtype(diags[0])
+
+	case StageVet:
+		return ErrorTypeGoType, SubtypeVet
+
+	case StageTest:
+		for _, d := range diags {
+			switch s := DiagnosticSubtype(d); s {
+			case SubtypePanic, SubtypeTimeout, SubtypeDeadlock:
+				return ErrorTypeGoRuntime, s
+			}
+		}
+		return ErrorTypeGoLogic, SubtypeAssertionFailure
+	}
+
+	return ErrorTypeGoUnknown, SubtypeNone
+}
@@ -0,0 +1,124 @@
+package go_compiler_v2
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestClassifyGoError(t *testing.T) {
+	tests := []struct {
+		name        string
+		stage       Stage
+		diags       []Diagnostic
+		wantType    ErrorTypeGo
+		wantSubtype ErrorSubtypeGo
+	}{
+		{
+			name:        "module setup",
+			stage:       StageModule,
+			wantType:    ErrorTypeGoInfrastructure,
+			wantSubtype: SubtypeMissingModule,
+		},
+		{
+			name:  "syntax error wins over earlier type errors",
+			stage: StageBuild,
+			diags: []Diagnostic{
+				{File: "main.go", Line: 3, Message: "undefined: x", Phase: PhaseBuild},
+				{File: "main.go", Line: 9, Message: "syntax error: unexpected }", Phase: PhaseBuild},
+			},
+			wantType:    ErrorTypeGoSyntax,
+			wantSubtype: SubtypeSyntax,
+		},
+		{
+			name:        "unused import",
+			stage:       StageBuild,
+			diags:       []Diagnostic{{File: "main.go", Message: `"os" imported and not used`, Phase: PhaseBuild}},
+			wantType:    ErrorTypeGoType,
+			wantSubtype: SubtypeUnusedImport,
+		},
+		{
+			name:        "missing return in test compile",
+			stage:       StageTestCompile,
+			diags:       []Diagnostic{{File: "main.go", Message: "missing return", Phase: PhaseBuild}},
+			wantType:    ErrorTypeGoType,
+			wantSubtype: SubtypeMissingReturn,
+		},
+		{
+			name:        "vet",
+			stage:       StageVet,
+			diags:       []Diagnostic{{File: "main_test.go", Message: "Errorf format %d has arg x of wrong type string", Phase: PhaseVet}},
+			wantType:    ErrorTypeGoType,
+			wantSubtype: SubtypeVet,
+		},
+		{
+			// Used to come out as a syntax or type error because the
+			// message contains "expected" and "type".
+			name:        "assertion mentioning type and expected",
+			stage:       StageTest,
+			diags:       []Diagnostic{{File: "main_test.go", Message: "expected type int, got unexpected value", Phase: PhaseTest, Test: "TestX"}},
+			wantType:    ErrorTypeGoLogic,
+			wantSubtype: SubtypeAssertionFailure,
+		},
+		{
+			name:  "panic",
+			stage: StageTest,
+			diags: []Diagnostic{
+				{File: "main_test.go", Message: "want 1", Phase: PhaseTest, Test: "TestA"},
+				{File: "main.go", Message: "panic: runtime error: integer divide by zero", Phase: PhaseTest, Test: "TestB"},
+			},
+			wantType:    ErrorTypeGoRuntime,
+			wantSubtype: SubtypePanic,
+		},
+		{
+			name:        "test timeout",
+			stage:       StageTest,
+			diags:       []Diagnostic{{Message: "panic: test timed out after 10s", Phase: PhaseTest}},
+			wantType:    ErrorTypeGoRuntime,
+			wantSubtype: SubtypeTimeout,
+		},
+		{
+			name:        "deadlock",
+			stage:       StageTest,
+			diags:       []Diagnostic{{Message: "fatal error: all goroutines are asleep - deadlock!", Phase: PhaseTest}},
+			wantType:    ErrorTypeGoRuntime,
+			wantSubtype: SubtypeDeadlock,
+		},
+		{
+			name:        "unknown stage",
+			stage:       StageNone,
+			wantType:    ErrorTypeGoUnknown,
+			wantSubtype: SubtypeNone,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotType, gotSubtype := ClassifyGoError(test.stage, test.diags)
+			if gotType != test.wantType || gotSubtype != test.wantSubtype {
+				t.Errorf("ClassifyGoError = (%v, %q), want (%v, %q)", gotType, gotSubtype, test.wantType, test.wantSubtype)
+			}
+		})
+	}
+}
+
+func TestCompileClassifiesFailingTest(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	main := "package main\n\nfunc Double(n int) int {\n\treturn n + 2\n}\n\nfunc main() {}\n"
+	test := "package main\n\nimport \"testing\"\n\nfunc TestDouble(t *testing.T) {\n\tif got := Double(5); got != 10 {\n\t\tt.Errorf(\"expected type of result to be 10, got unexpected %d\", got)\n\t}\n}\n"
+
+	result, err := NewGoCompilerV2().Compile(ctx, main, test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ErrorType != ErrorTypeGoLogic || result.Stage != StageTest || result.ErrorSubtype != SubtypeAssertionFailure {
+		t.Errorf("got (%v, %q, %q), want logic error in test stage\noutput:\n%s", result.ErrorType, result.Stage, result.ErrorSubtype, result.RawOutput)
+	}
+}
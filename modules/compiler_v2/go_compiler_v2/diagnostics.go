@@ -0,0 +1,228 @@
+package go_compiler_v2
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// STRUCTURED DIAGNOSTICS
+// ============================================================================
+
+// DiagnosticPhase is the toolchain step that reported a diagnostic
+type DiagnosticPhase string
+
+const (
+	PhaseBuild DiagnosticPhase = "build" // go build / test binary compile
+	PhaseVet   DiagnosticPhase = "vet"   // go vet checks run by go test
+	PhaseTest  DiagnosticPhase = "test"  // t.Error output, panics
+)
+
+// Diagnostic is a single located message from the Go toolchain
+type Diagnostic struct {
+	File    string // Workspace-relative file name, e.g. "main.go"
+	Line    int
+	Column  int // 0 when the tool doesn't report one (test logs, stacks)
+	Message string
+	Phase   DiagnosticPhase
+	Test    string // Test that produced it, for PhaseTest
+	Source  string // The offending source line, if known
+}
+
+func (d Diagnostic) String() string {
+	var loc string
+	switch {
+	case d.File == "":
+	case d.Column > 0:
+		loc = fmt.Sprintf("%s:%d:%d: ", d.File, d.Line, d.Column)
+	case d.Line > 0:
+		loc = fmt.Sprintf("%s:%d: ", d.File, d.Line)
+	default:
+		loc = d.File + ": "
+	}
+	if d.Test != "" {
+		return d.Test + ": " + loc + d.Message
+	}
+	return loc + d.Message
+}
+
+var (
+	// ./main.go:6:13: undefined: c
+	compilerDiagRe = regexp.MustCompile(`^(?:\./)?([^\s:]+\.go):(\d+):(\d+): (.+)$`)
+	// "    main_test.go:7: got 3" as printed by t.Errorf
+	testLogRe = regexp.MustCompile(`^(\s+)([^\s:]+\.go):(\d+): ?(.*)$`)
+	// "\t/tmp/go_compile_1/main.go:11 +0x9" in a goroutine trace
+	stackFrameRe = regexp.MustCompile(`^\t(\S+\.go):(\d+)`)
+	runLineRe    = regexp.MustCompile(`^=== (?:RUN|CONT)\s+(\S+)`)
+	failLineRe   = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+	recoveredRe  = regexp.MustCompile(`\s*\[recovered.*\]$`)
+)
+
+// ParseDiagnostics turns go build, vet and test output into diagnostics.
+// sources maps workspace file names to their contents. It is used to attach
+// the offending line and to pick the user's frame out of a panic's stack.
+func ParseDiagnostics(output string, sources map[string]string) []Diagnostic {
+	var diags []Diagnostic
+	seen := make(map[string]bool)
+
+	add := func(d Diagnostic) {
+		key := fmt.Sprintf("%s|%s|%d|%d|%s", d.Test, d.File, d.Line, d.Column, d.Message)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		d.Source = sourceLine(sources, d.File, d.Line)
+		diags = append(diags, d)
+	}
+
+	phase := PhaseBuild
+	currentTest := ""
+	failedWithMessage := make(map[string]bool)
+	var failedTests []string
+
+	// State for multi-line t.Errorf messages
+	lastTestDiag := -1
+	lastIndent := 0
+
+	// State for a panic whose location comes from the following stack
+	var pendingPanic *Diagnostic
+
+	flushPanic := func() {
+		if pendingPanic != nil {
+			add(*pendingPanic)
+			pendingPanic = nil
+		}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if pendingPanic != nil {
+			if m := stackFrameRe.FindStringSubmatch(line); m != nil {
+				file := filepath.Base(m[1])
+				if _, ok := sources[file]; ok && pendingPanic.File == "" {
+					pendingPanic.File = file
+					pendingPanic.Line, _ = strconv.Atoi(m[2])
+				}
+				continue
+			}
+			if line == "" || strings.HasPrefix(line, "goroutine ") || strings.HasSuffix(line, ")") || strings.HasPrefix(line, "created by ") {
+				continue
+			}
+			flushPanic()
+		}
+
+		switch {
+		case strings.HasPrefix(line, "# ["):
+			phase = PhaseVet
+			continue
+		case strings.HasPrefix(line, "# "):
+			phase = PhaseBuild
+			continue
+		}
+
+		if m := runLineRe.FindStringSubmatch(line); m != nil {
+			phase = PhaseTest
+			currentTest = m[1]
+			lastTestDiag = -1
+			continue
+		}
+
+		if m := failLineRe.FindStringSubmatch(line); m != nil {
+			failedTests = append(failedTests, m[1])
+			lastTestDiag = -1
+			continue
+		}
+
+		if strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ") {
+			msg := recoveredRe.ReplaceAllString(line, "")
+			pendingPanic = &Diagnostic{Message: msg, Phase: PhaseTest, Test: currentTest}
+			if currentTest != "" {
+				failedWithMessage[currentTest] = true
+			}
+			continue
+		}
+
+		if m := compilerDiagRe.FindStringSubmatch(line); m != nil {
+			lineNo, _ := strconv.Atoi(m[2])
+			col, _ := strconv.Atoi(m[3])
+			p := phase
+			if p == PhaseTest {
+				p = PhaseBuild
+			}
+			add(Diagnostic{File: filepath.Base(m[1]), Line: lineNo, Column: col, Message: m[4], Phase: p})
+			continue
+		}
+
+		if m := testLogRe.FindStringSubmatch(line); m != nil && phase == PhaseTest {
+			lineNo, _ := strconv.Atoi(m[3])
+			add(Diagnostic{File: filepath.Base(m[2]), Line: lineNo, Message: m[4], Phase: PhaseTest, Test: currentTest})
+			failedWithMessage[currentTest] = true
+			lastTestDiag = len(diags) - 1
+			lastIndent = len(m[1])
+			continue
+		}
+
+		// Continuation of a multi-line t.Errorf message is indented further
+		// than the line that carried the location.
+		if lastTestDiag >= 0 && lastTestDiag == len(diags)-1 {
+			trimmed := strings.TrimLeft(line, " \t")
+			if trimmed != "" && len(line)-len(trimmed) > lastIndent {
+				diags[lastTestDiag].Message += "\n" + trimmed
+				continue
+			}
+		}
+		lastTestDiag = -1
+	}
+	flushPanic()
+
+	// Tests that failed without logging anything (t.Fail, t.FailNow) still
+	// get a diagnostic, unless one of their subtests already explains it.
+	for _, name := range failedTests {
+		if !hasMessage(failedWithMessage, name) {
+			add(Diagnostic{Message: "test failed", Phase: PhaseTest, Test: name})
+		}
+	}
+
+	return diags
+}
+
+func hasMessage(failedWithMessage map[string]bool, test string) bool {
+	if failedWithMessage[test] {
+		return true
+	}
+	for name := range failedWithMessage {
+		if strings.HasPrefix(name, test+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceLine returns line n (1-based) of the named source, trimmed
+func sourceLine(sources map[string]string, file string, n int) string {
+	src, ok := sources[file]
+	if !ok || n <= 0 {
+		return ""
+	}
+	lines := strings.Split(src, "\n")
+	if n > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[n-1])
+}
+
+// splitDiagnostics formats diagnostics into the legacy compile/test error lists
+func splitDiagnostics(diags []Diagnostic) (compileErrors, testErrors []string) {
+	for _, d := range diags {
+		if d.Phase == PhaseTest {
+			testErrors = append(testErrors, d.String())
+		} else {
+			compileErrors = append(compileErrors, d.String())
+		}
+	}
+	return compileErrors, testErrors
+}
@@ -0,0 +1,164 @@
+package go_compiler_v2
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+const diagMain = `package main
+
+import "os"
+
+func Add(a, b int) int {
+	return a + c
+}
+
+func main() {}
+`
+
+const diagTest = `package main
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 4 {
+		t.Errorf("got %d\nwant 4", Add(1, 2))
+	}
+	x := 5
+}
+`
+
+func TestParseDiagnosticsBuildErrors(t *testing.T) {
+	output := `# temp_module [temp_module.test]
+./main.go:3:8: "os" imported and not used
+./main.go:6:13: undefined: c
+./main_test.go:9:2: declared and not used: x
+FAIL	temp_module [build failed]
+FAIL
+`
+	diags := ParseDiagnostics(output, map[string]string{"main.go": diagMain, "main_test.go": diagTest})
+
+	want := []Diagnostic{
+		{File: "main.go", Line: 3, Column: 8, Message: `"os" imported and not used`, Phase: PhaseBuild, Source: `import "os"`},
+		{File: "main.go", Line: 6, Column: 13, Message: "undefined: c", Phase: PhaseBuild, Source: "return a + c"},
+		{File: "main_test.go", Line: 9, Column: 2, Message: "declared and not used: x", Phase: PhaseBuild, Source: "x := 5"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %+v", len(diags), len(want), diags)
+	}
+	for i := range want {
+		if diags[i] != want[i] {
+			t.Errorf("diagnostic %d = %+v, want %+v", i, diags[i], want[i])
+		}
+	}
+}
+
+func TestParseDiagnosticsVet(t *testing.T) {
+	output := `# temp_module
+# [temp_module]
+./main_test.go:12:36: (*testing.common).Logf format %d has arg "x" of wrong type string
+FAIL	temp_module [build failed]
+`
+	diags := ParseDiagnostics(output, nil)
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	if diags[0].Phase != PhaseVet || diags[0].File != "main_test.go" || diags[0].Line != 12 || diags[0].Column != 36 {
+		t.Errorf("unexpected diagnostic %+v", diags[0])
+	}
+}
+
+func TestParseDiagnosticsTestFailures(t *testing.T) {
+	testCode := "package main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 4 {\n\t\tt.Errorf(\"got %d\\nwant 4\", Add(1, 2))\n\t}\n}\n"
+	output := `=== RUN   TestAdd
+    main_test.go:7: got 3
+        want 4
+--- FAIL: TestAdd (0.00s)
+=== RUN   TestQuiet
+--- FAIL: TestQuiet (0.00s)
+=== RUN   TestPanics
+--- FAIL: TestPanics (0.00s)
+panic: runtime error: index out of range [3] with length 0 [recovered, repanicked]
+
+goroutine 8 [running]:
+testing.tRunner.func1.2({0x6c8d90, 0x25121b9880d8})
+	/usr/local/go/src/testing/testing.go:2123 +0x232
+panic({0x6c8d90?, 0x25121b9880d8?})
+	/usr/local/go/src/runtime/panic.go:859 +0x125
+temp_module.TestPanics(0x25121ba06488?)
+	/tmp/go_compile_1/main_test.go:11 +0x9
+testing.tRunner(0x25121ba06488, 0x6d44d0)
+	/usr/local/go/src/testing/testing.go:2193 +0xea
+created by testing.(*T).Run in goroutine 1
+	/usr/local/go/src/testing/testing.go:2258 +0x4d4
+FAIL	temp_module	0.005s
+FAIL
+`
+	diags := ParseDiagnostics(output, map[string]string{"main.go": "", "main_test.go": testCode})
+	if len(diags) != 3 {
+		t.Fatalf("got %d diagnostics, want 3: %+v", len(diags), diags)
+	}
+
+	if d := diags[0]; d.Test != "TestAdd" || d.File != "main_test.go" || d.Line != 7 || d.Message != "got 3\nwant 4" || d.Phase != PhaseTest {
+		t.Errorf("assertion diagnostic = %+v", d)
+	}
+	if d := diags[0]; d.Source != `t.Errorf("got %d\nwant 4", Add(1, 2))` {
+		t.Errorf("assertion source = %q", d.Source)
+	}
+	if d := diags[1]; d.Test != "TestPanics" || d.File != "main_test.go" || d.Line != 11 ||
+		d.Message != "panic: runtime error: index out of range [3] with length 0" {
+		t.Errorf("panic diagnostic = %+v", d)
+	}
+	if d := diags[2]; d.Test != "TestQuiet" || d.File != "" || d.Message != "test failed" {
+		t.Errorf("silent failure diagnostic = %+v", d)
+	}
+}
+
+func TestParseDiagnosticsSubtests(t *testing.T) {
+	output := `=== RUN   TestTable
+=== RUN   TestTable/negative
+    main_test.go:14: Abs(-1) = -1, want 1
+--- FAIL: TestTable (0.00s)
+    --- FAIL: TestTable/negative (0.00s)
+FAIL
+`
+	diags := ParseDiagnostics(output, nil)
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	if diags[0].Test != "TestTable/negative" {
+		t.Errorf("diagnostic attributed to %q", diags[0].Test)
+	}
+}
+
+func TestCompileReportsDiagnostics(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := NewGoCompilerV2().Compile(ctx, diagMain, diagTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success {
+		t.Fatal("expected failure")
+	}
+
+	var found bool
+	for _, d := range result.Diagnostics {
+		if d.File == "main.go" && d.Line == 6 && d.Source == "return a + c" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a located diagnostic for the undefined symbol, got %+v\noutput:\n%s", result.Diagnostics, result.RawOutput)
+	}
+	if len(result.CompileErrors) != len(result.Diagnostics) {
+		t.Errorf("CompileErrors %v don't mirror diagnostics", result.CompileErrors)
+	}
+}
@@ -1,11 +1,9 @@
 package go_compiler_v2
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -15,7 +13,10 @@ import (
 // IMPROVED GO COMPILER WITH PROPER ERROR CLASSIFICATION
 // ============================================================================
 
-type GoCompilerV2 struct{}
+type GoCompilerV2 struct {
+	Env     []string    // Added to the environment of every go command, e.g. GOPROXY=off
+	OnStage func(Stage) // Called, if set, as each stage that runs starts
+}
 
 func NewGoCompilerV2() *GoCompilerV2 {
 	return &GoCompilerV2{}
@@ -29,9 +30,14 @@ type CompilationResultV2 struct {
 	TestOutput    string
 	RawOutput     string
 	ErrorType     ErrorTypeGo
+	ErrorSubtype  ErrorSubtypeGo
+	Stage         Stage // Stage that failed, StageNone on success
 	ExecutionTime time.Duration
 	CompileErrors []string
 	TestErrors    []string
+	Diagnostics   []Diagnostic
+	Stages        []StageResult // Every stage in run order, including skipped ones
+	TestReport    *TestReport   // Per-test results, nil if the tests didn't run
 }
 
 // ErrorTypeGo classifies Go compilation errors
@@ -47,12 +53,12 @@ const (
 	ErrorTypeGoSuccess                    // No error
 )
 
-// Compile executes Go compilation with proper isolation and error handling
+// Compile builds and tests the code stage by stage in a fresh workspace.
+// Each stage runs on its own so its output, exit code and timing are kept;
+// once a stage fails the rest are recorded as skipped.
 func (gc *GoCompilerV2) Compile(ctx context.Context, mainCode, testCode string) (*CompilationResultV2, error) {
 	startTime := time.Now()
-	result := &CompilationResultV2{
-		ExecutionTime: time.Since(startTime),
-	}
+	result := &CompilationResultV2{}
 
 	// Create fresh temp directory
 	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("go_compile_%d", time.Now().UnixNano()))
@@ -64,179 +70,124 @@ func (gc *GoCompilerV2) Compile(ctx context.Context, mainCode, testCode string)
 	}
 	defer os.RemoveAll(tempDir)
 
-	// Write files
-	mainFile := filepath.Join(tempDir, "main.go")
-	testFile := filepath.Join(tempDir, "main_test.go")
-
-	if err := os.WriteFile(mainFile, []byte(mainCode), 0644); err != nil {
-		result.ErrorType = ErrorTypeGoInfrastructure
-		result.RawOutput = fmt.Sprintf("Failed to write main.go: %v", err)
-		result.CompileErrors = append(result.CompileErrors, result.RawOutput)
-		return result, nil
-	}
-
-	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
-		result.ErrorType = ErrorTypeGoInfrastructure
-		result.RawOutput = fmt.Sprintf("Failed to write main_test.go: %v", err)
-		result.CompileErrors = append(result.CompileErrors, result.RawOutput)
-		return result, nil
+	// Write files. Without test code there is no main_test.go, since an
+	// empty one would fail to parse.
+	hasTests := strings.TrimSpace(testCode) != ""
+	sources := map[string]string{"main.go": mainCode}
+	if hasTests {
+		sources["main_test.go"] = testCode
 	}
 
-	// Execute compilation pipeline with timeout
-	cmdString := "go mod init temp_module && go mod tidy && go build -o /dev/null . && go test -v"
-
-	// Parse command with error handling
-	shell := "bash"
-	shellArg := "-c"
-
-	cmd := exec.CommandContext(ctx, shell, shellArg, cmdString)
-	cmd.Dir = tempDir
-
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Run with timeout
-	doneChan := make(chan error, 1)
-	go func() {
-		doneChan <- cmd.Run()
-	}()
-
-	select {
-	case <-ctx.Done():
-		cmd.Process.Kill()
-		result.ErrorType = ErrorTypeGoInfrastructure
-		result.RawOutput = "Compilation timeout"
-		result.CompileErrors = append(result.CompileErrors, "Compilation exceeded timeout")
-		result.ExecutionTime = time.Since(startTime)
-		return result, nil
-
-	case err := <-doneChan:
-		result.ExecutionTime = time.Since(startTime)
-
-		fullOutput := stdout.String() + "\n" + stderr.String()
-		result.RawOutput = fullOutput
-
-		if err == nil {
-			result.Success = true
-			result.ErrorType = ErrorTypeGoSuccess
-			result.ExitCode = 0
+	for name, code := range sources {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(code), 0644); err != nil {
+			result.ErrorType = ErrorTypeGoInfrastructure
+			result.RawOutput = fmt.Sprintf("Failed to write %s: %v", name, err)
+			result.CompileErrors = append(result.CompileErrors, result.RawOutput)
 			return result, nil
 		}
-
-		// Parse error
-		result.ExitCode = 1
-		result.CompileErrors, result.TestErrors = parseGoErrors(fullOutput)
-
-		// Classify error
-		result.ErrorType = classifyGoError(fullOutput, result.CompileErrors, result.TestErrors)
-
-		return result, nil
 	}
-}
 
-// ============================================================================
-// ERROR PARSING & CLASSIFICATION
-// ============================================================================
-
-// parseGoErrors separates compilation errors from test errors
-func parseGoErrors(output string) ([]string, []string) {
-	var compileErrors []string
-	var testErrors []string
-
-	lines := strings.Split(output, "\n")
+	// Run the stages
+	var failed *StageResult
+	for _, spec := range goStages(testTimeoutFor(ctx)) {
+		if failed != nil || ctx.Err() != nil || (spec.tests && !hasTests) {
+			result.Stages = append(result.Stages, StageResult{
+				Stage:   spec.stage,
+				Command: "go " + strings.Join(spec.args, " "),
+				Status:  StageStatusSkipped,
+			})
+			continue
+		}
 
-	for _, line := range lines {
-		// Compilation errors typically contain file:line:col
-		if strings.Contains(line, "main.go:") {
-			compileErrors = append(compileErrors, strings.TrimSpace(line))
+		if gc.OnStage != nil {
+			gc.OnStage(spec.stage)
+		}
+		sr := runStage(ctx, tempDir, gc.Env, spec)
+		if spec.stage == StageTest {
+			// Keep the report and show the -v style text instead of JSON
+			result.TestReport, sr.Output = ParseTestJSON(sr.Output)
 		}
 
-		// Test failures
-		if strings.Contains(line, "FAIL") || strings.Contains(line, "Error:") {
-			testErrors = append(testErrors, strings.TrimSpace(line))
+		result.Stages = append(result.Stages, sr)
+		if sr.Status == StageStatusFailed {
+			failed = &result.Stages[len(result.Stages)-1]
 		}
 	}
 
-	return compileErrors, testErrors
-}
-
-// classifyGoError determines the type of error
-func classifyGoError(output string, compileErrors, testErrors []string) ErrorTypeGo {
-	output = strings.ToLower(output)
-
-	// Infrastructure errors
-	infraPatterns := []string{
-		"go.mod already exists",
-		"cannot find package",
-		"not in GOPATH",
-		"invalid go.mod",
-		"module not found",
-		"go get",
+	result.ExecutionTime = time.Since(startTime)
+	result.RawOutput = formatStages(result.Stages)
+	for _, sr := range result.Stages {
+		if sr.Stage == StageTest {
+			result.TestOutput += sr.Output
+		} else {
+			result.CompileOutput += sr.Output
+		}
 	}
 
-	for _, pattern := range infraPatterns {
-		if strings.Contains(output, strings.ToLower(pattern)) {
-			return ErrorTypeGoInfrastructure
+	if ctx.Err() != nil {
+		result.ErrorType = ErrorTypeGoInfrastructure
+		if failed != nil {
+			result.Stage = failed.Stage
 		}
+		result.ExitCode = -1
+		result.CompileErrors = append(result.CompileErrors, "Compilation exceeded timeout")
+		return result, nil
 	}
 
-	// Syntax errors
-	syntaxPatterns := []string{
-		"syntax error",
-		"expected",
-		"unexpected",
-		"invalid operation",
-		"unclosed",
+	if failed == nil {
+		result.Success = true
+		result.ErrorType = ErrorTypeGoSuccess
+		result.ExitCode = 0
+		return result, nil
 	}
 
-	for _, pattern := range syntaxPatterns {
-		if strings.Contains(output, strings.ToLower(pattern)) {
-			return ErrorTypeGoSyntax
-		}
-	}
+	result.Stage = failed.Stage
+	result.ExitCode = failed.ExitCode
 
-	// Type errors
-	typePatterns := []string{
-		"undefined",
-		"type",
-		"cannot use",
-		"mismatched types",
-		"wrong number of arguments",
-		"not a function",
+	if failed.ExitCode == -1 {
+		// The go command itself couldn't be started
+		result.ErrorType = ErrorTypeGoInfrastructure
+		result.CompileErrors = nonEmptyLines(failed.Output)
+		return result, nil
 	}
 
-	for _, pattern := range typePatterns {
-		if strings.Contains(output, strings.ToLower(pattern)) {
-			return ErrorTypeGoType
+	// Parse error
+	result.Diagnostics = ParseDiagnostics(failed.Output, sources)
+	if failed.Stage != StageTest {
+		// The stage is known exactly, so it decides the phase
+		phase := PhaseBuild
+		if failed.Stage == StageVet {
+			phase = PhaseVet
+		}
+		for i := range result.Diagnostics {
+			result.Diagnostics[i].Phase = phase
 		}
 	}
-
-	// Runtime errors
-	runtimePatterns := []string{
-		"panic",
-		"fatal",
-		"signal: segmentation",
-		"runtime error",
+	result.CompileErrors, result.TestErrors = splitDiagnostics(result.Diagnostics)
+	if len(result.Diagnostics) == 0 {
+		// Nothing located (e.g. go mod tidy failed); keep the raw lines
+		result.CompileErrors = nonEmptyLines(failed.Output)
 	}
 
-	for _, pattern := range runtimePatterns {
-		if strings.Contains(output, strings.ToLower(pattern)) {
-			return ErrorTypeGoRuntime
-		}
-	}
+	// Classify error by the stage that failed
+	result.ErrorType, result.ErrorSubtype = ClassifyGoError(result.Stage, result.Diagnostics)
 
-	// Test failures
-	if len(testErrors) > 0 {
-		return ErrorTypeGoLogic
-	}
+	return result, nil
+}
 
-	if len(compileErrors) > 0 {
-		return ErrorTypeGoType // Default to type error if unclear
-	}
+// ============================================================================
+// ERROR PARSING & CLASSIFICATION
+// ============================================================================
 
-	return ErrorTypeGoUnknown
+// nonEmptyLines returns the trimmed, non-blank lines of output
+func nonEmptyLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
 }
 
 // ============================================================================
@@ -267,27 +218,6 @@ func CheckCompileErrors(srcCode, testCode string) ([]byte, error) {
 	return []byte(output), fmt.Errorf("compilation failed: %s", result.RawOutput)
 }
 
-// ============================================================================
-// ERROR RECOVERY STRATEGIES
-// ============================================================================
-
-// RetryCompileWithClean retries compilation with a completely clean state
-func (gc *GoCompilerV2) RetryCompileWithClean(ctx context.Context, mainCode, testCode string) (*CompilationResultV2, error) {
-	// First attempt
-	result, err := gc.Compile(ctx, mainCode, testCode)
-	if err != nil {
-		return result, err
-	}
-
-	// If infrastructure error, retry is unlikely to help
-	if result.ErrorType == ErrorTypeGoInfrastructure {
-		return result, nil
-	}
-
-	// Otherwise return the result
-	return result, nil
-}
-
 // ============================================================================
 // ERROR MESSAGE FORMATTING FOR LLM
 // ============================================================================
@@ -300,6 +230,9 @@ func FormatErrorForLLM(result *CompilationResultV2) string {
 
 	var msg strings.Builder
 	msg.WriteString(fmt.Sprintf("Error Type: %v\n", result.ErrorType))
+	if result.Stage != StageNone {
+		msg.WriteString(fmt.Sprintf("Failed Stage: %s (%s)\n", result.Stage, result.ErrorSubtype))
+	}
 
 	if len(result.CompileErrors) > 0 {
 		msg.WriteString("Compilation Errors:\n")
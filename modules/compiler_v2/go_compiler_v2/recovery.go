@@ -0,0 +1,91 @@
+package go_compiler_v2
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// ============================================================================
+// ERROR RECOVERY STRATEGIES
+// ============================================================================
+
+// RecoveryAction is a way around an infrastructure failure that doesn't
+// involve changing the code
+type RecoveryAction string
+
+const (
+	// RecoverFreshWorkspace compiles again in a new temp directory
+	RecoverFreshWorkspace RecoveryAction = "fresh_workspace"
+	// RecoverOfflineModules resolves imports from the module cache only, for
+	// when go mod tidy fails because the proxy can't be reached
+	RecoverOfflineModules RecoveryAction = "offline_tidy"
+	// RecoverFreshCache compiles again with an empty build cache of its
+	// own, for when the shared one is corrupt. Clearing the shared cache
+	// instead would pull it from under every other build in progress.
+	RecoverFreshCache RecoveryAction = "fresh_build_cache"
+)
+
+// offlineEnv keeps the go command off the network
+var offlineEnv = []string{"GOPROXY=off", "GOSUMDB=off", "GOFLAGS=-mod=mod"}
+
+// RecoveryActions lists the actions worth trying for a failed result, in
+// the order they should be tried
+func RecoveryActions(result *CompilationResultV2) []RecoveryAction {
+	if result.Success || result.ErrorType != ErrorTypeGoInfrastructure {
+		return nil
+	}
+	actions := []RecoveryAction{RecoverFreshWorkspace}
+	if result.Stage == StageModule {
+		actions = append(actions, RecoverOfflineModules)
+	}
+	return append(actions, RecoverFreshCache)
+}
+
+// Recover performs action and compiles the code again
+func (gc *GoCompilerV2) Recover(ctx context.Context, action RecoveryAction, mainCode, testCode string) (*CompilationResultV2, error) {
+	compiler := &GoCompilerV2{Env: gc.Env}
+
+	switch action {
+	case RecoverFreshWorkspace:
+		// Compile always starts from a new temp directory
+	case RecoverOfflineModules:
+		compiler.Env = append(append([]string{}, gc.Env...), offlineEnv...)
+	case RecoverFreshCache:
+		cacheDir, err := os.MkdirTemp("", "gocache-")
+		if err != nil {
+			return nil, fmt.Errorf("creating build cache: %w", err)
+		}
+		defer os.RemoveAll(cacheDir)
+		compiler.Env = append(append([]string{}, gc.Env...), "GOCACHE="+cacheDir)
+	default:
+		return nil, fmt.Errorf("unknown recovery action %q", action)
+	}
+
+	return compiler.Compile(ctx, mainCode, testCode)
+}
+
+// RetryCompileWithClean compiles the code and, on an infrastructure failure,
+// works through the recovery actions until one of them gets past it
+func (gc *GoCompilerV2) RetryCompileWithClean(ctx context.Context, mainCode, testCode string) (*CompilationResultV2, error) {
+	result, err := gc.Compile(ctx, mainCode, testCode)
+	if err != nil {
+		return result, err
+	}
+
+	for _, action := range RecoveryActions(result) {
+		if ctx.Err() != nil {
+			break
+		}
+		retry, err := gc.Recover(ctx, action, mainCode, testCode)
+		if err != nil {
+			continue
+		}
+		result = retry
+		if result.ErrorType != ErrorTypeGoInfrastructure {
+			break
+		}
+	}
+
+	return result, nil
+}
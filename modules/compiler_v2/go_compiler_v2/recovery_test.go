@@ -0,0 +1,97 @@
+package go_compiler_v2
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecoveryActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *CompilationResultV2
+		want   []RecoveryAction
+	}{
+		{"success", &CompilationResultV2{Success: true, ErrorType: ErrorTypeGoSuccess}, nil},
+		{"code error", &CompilationResultV2{ErrorType: ErrorTypeGoType, Stage: StageBuild}, nil},
+		{"module", &CompilationResultV2{ErrorType: ErrorTypeGoInfrastructure, Stage: StageModule},
+			[]RecoveryAction{RecoverFreshWorkspace, RecoverOfflineModules, RecoverFreshCache}},
+		{"workspace", &CompilationResultV2{ErrorType: ErrorTypeGoInfrastructure},
+			[]RecoveryAction{RecoverFreshWorkspace, RecoverFreshCache}},
+	}
+	for _, test := range tests {
+		if got := RecoveryActions(test.result); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: RecoveryActions = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRecoverOfflineModules(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// An import that can't be in the module cache fails in the module stage
+	compiler := &GoCompilerV2{Env: []string{"GOPROXY=off"}}
+	missing := "package main\n\nimport _ \"example.invalid/nope\"\n\nfunc main() {}\n"
+	result, err := compiler.Compile(ctx, missing, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ErrorType != ErrorTypeGoInfrastructure || result.Stage != StageModule {
+		t.Fatalf("got %v in stage %q, want an infrastructure error in the module stage:\n%s", result.ErrorType, result.Stage, result.RawOutput)
+	}
+
+	// Standard library code builds with the network switched off
+	result, err = compiler.Recover(ctx, RecoverOfflineModules, stagesMain, stagesTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success {
+		t.Errorf("offline compile failed:\n%s", result.RawOutput)
+	}
+
+	if _, err := compiler.Recover(ctx, "reboot", stagesMain, stagesTest); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
+
+func TestRecoverFreshCache(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// A build cache that can't be used fails every build that shares it
+	blocked := filepath.Join(t.TempDir(), "not-a-directory")
+	if err := os.WriteFile(blocked, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compiler := &GoCompilerV2{Env: []string{"GOPROXY=off", "GOCACHE=" + blocked}}
+	result, err := compiler.Compile(ctx, stagesMain, stagesTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success {
+		t.Fatal("compiled with an unusable build cache")
+	}
+
+	// The recovery builds with a cache of its own and leaves the shared one be
+	result, err = compiler.Recover(ctx, RecoverFreshCache, stagesMain, stagesTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success {
+		t.Errorf("compile with a fresh cache failed:\n%s", result.RawOutput)
+	}
+	if info, err := os.Stat(blocked); err != nil || info.IsDir() {
+		t.Errorf("shared cache was touched: %v, %v", info, err)
+	}
+}
@@ -0,0 +1,147 @@
+package go_compiler_v2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// BUILD STAGES
+// ============================================================================
+
+// StageStatus is the outcome of a single stage
+type StageStatus string
+
+const (
+	StageStatusOK      StageStatus = "ok"
+	StageStatusFailed  StageStatus = "failed"
+	StageStatusSkipped StageStatus = "skipped" // An earlier stage failed, or there are no tests
+)
+
+// StageResult records one toolchain invocation
+type StageResult struct {
+	Stage    Stage
+	Command  string
+	Output   string
+	ExitCode int // -1 if the command could not be run or was killed
+	Duration time.Duration
+	Status   StageStatus
+}
+
+// stageSpec is a go subcommand to run for a stage
+type stageSpec struct {
+	stage Stage
+	args  []string
+	tests bool // Only meaningful when there is test code
+}
+
+// vetAnalyzers is the high-confidence subset of go vet checks that go test
+// runs; the full default set would reject code go test accepts.
+var vetAnalyzers = []string{
+	"-atomic", "-bools", "-buildtag", "-directive", "-errorsas",
+	"-ifaceassert", "-nilfunc", "-printf", "-stringintconv", "-tests",
+}
+
+// goStages lists the stages in the order they run. testTimeout is passed to
+// go test so a hanging test dumps its goroutines before ctx kills it.
+func goStages(testTimeout time.Duration) []stageSpec {
+	testArgs := []string{"test", "-vet=off", "-json"}
+	if testTimeout > 0 {
+		testArgs = append(testArgs, "-timeout="+testTimeout.String())
+	}
+	testArgs = append(testArgs, ".")
+
+	return []stageSpec{
+		{stage: StageModule, args: []string{"mod", "init", "temp_module"}},
+		{stage: StageModule, args: []string{"mod", "tidy"}},
+		// -gcflags=-e reports every error instead of stopping after ten
+		{stage: StageBuild, args: []string{"build", "-gcflags=-e", "-o", os.DevNull, "."}},
+		{stage: StageTestCompile, args: []string{"test", "-c", "-vet=off", "-gcflags=-e", "-o", os.DevNull, "."}, tests: true},
+		{stage: StageVet, args: append(append([]string{"vet"}, vetAnalyzers...), ".")},
+		{stage: StageTest, args: testArgs, tests: true},
+	}
+}
+
+// testTimeoutFor leaves a margin before ctx's deadline for go test to print
+// the timeout panic, or returns 0 to use go test's default
+func testTimeoutFor(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	remaining := time.Until(deadline) - 2*time.Second
+	if remaining < time.Second {
+		return 0
+	}
+	return remaining.Truncate(time.Second)
+}
+
+// runStage runs a go subcommand in dir, with env added to the environment,
+// and records its outcome
+func runStage(ctx context.Context, dir string, env []string, spec stageSpec) StageResult {
+	start := time.Now()
+
+	cmd := exec.CommandContext(ctx, "go", spec.args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	// Don't wait forever on pipes held open by a killed test binary
+	cmd.WaitDelay = time.Second
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+
+	sr := StageResult{
+		Stage:    spec.stage,
+		Command:  "go " + strings.Join(spec.args, " "),
+		Output:   out.String(),
+		Duration: time.Since(start),
+		Status:   StageStatusOK,
+	}
+
+	if err != nil {
+		sr.Status = StageStatusFailed
+		sr.ExitCode = -1
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			sr.ExitCode = exitErr.ExitCode()
+		} else {
+			sr.Output += err.Error()
+		}
+	}
+
+	return sr
+}
+
+// formatStages renders the stages as a transcript of the commands and their output
+func formatStages(stages []StageResult) string {
+	var b strings.Builder
+	for _, sr := range stages {
+		if sr.Status == StageStatusSkipped {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("$ %s  (%s, %s)\n", sr.Command, sr.Status, sr.Duration.Round(time.Millisecond)))
+		b.WriteString(sr.Output)
+		if sr.Output != "" && !strings.HasSuffix(sr.Output, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Version reports the go toolchain in use, e.g. "go version go1.22.1 linux/amd64"
+func Version(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "go", "version").Output()
+	return strings.TrimSpace(string(out)), err
+}
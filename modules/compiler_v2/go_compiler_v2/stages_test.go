@@ -0,0 +1,153 @@
+package go_compiler_v2
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const stagesMain = `package main
+
+func Double(n int) int {
+	return n * 2
+}
+
+func main() {}
+`
+
+const stagesTest = `package main
+
+import "testing"
+
+func TestDouble(t *testing.T) {
+	if got := Double(4); got != 8 {
+		t.Errorf("Double(4) = %d, want 8", got)
+	}
+}
+`
+
+func compileForTest(t *testing.T, mainCode, testCode string) *CompilationResultV2 {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := NewGoCompilerV2().Compile(ctx, mainCode, testCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// stageStatuses summarises the stages as "stage:status" pairs
+func stageStatuses(stages []StageResult) string {
+	var parts []string
+	for _, sr := range stages {
+		parts = append(parts, fmt.Sprintf("%s:%s", sr.Stage, sr.Status))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestCompileStagesSuccess(t *testing.T) {
+	result := compileForTest(t, stagesMain, stagesTest)
+	if !result.Success {
+		t.Fatalf("expected success, got:\n%s", result.RawOutput)
+	}
+
+	want := "module:ok module:ok build:ok test_compile:ok vet:ok test:ok"
+	if got := stageStatuses(result.Stages); got != want {
+		t.Errorf("stages = %s, want %s", got, want)
+	}
+	for _, sr := range result.Stages {
+		if sr.Duration <= 0 || sr.Command == "" {
+			t.Errorf("stage %s missing command or duration: %+v", sr.Stage, sr)
+		}
+	}
+	if !strings.Contains(result.TestOutput, "--- PASS: TestDouble") {
+		t.Errorf("TestOutput = %q", result.TestOutput)
+	}
+	if !strings.Contains(result.CompileOutput, "go.mod") {
+		t.Errorf("CompileOutput = %q", result.CompileOutput)
+	}
+}
+
+func TestCompileStagesWithoutTests(t *testing.T) {
+	result := compileForTest(t, stagesMain, "  \n")
+	if !result.Success {
+		t.Fatalf("expected success, got:\n%s", result.RawOutput)
+	}
+
+	want := "module:ok module:ok build:ok test_compile:skipped vet:ok test:skipped"
+	if got := stageStatuses(result.Stages); got != want {
+		t.Errorf("stages = %s, want %s", got, want)
+	}
+}
+
+func TestCompileStagesBuildFailureIsExhaustive(t *testing.T) {
+	// More than the ten errors the compiler reports without -gcflags=-e
+	var body strings.Builder
+	for i := 0; i < 12; i++ {
+		body.WriteString(fmt.Sprintf("\t_ = missing%d\n", i))
+	}
+	mainCode := "package main\n\nfunc main() {\n" + body.String() + "}\n"
+
+	result := compileForTest(t, mainCode, stagesTest)
+	if result.Success || result.Stage != StageBuild {
+		t.Fatalf("expected build failure, got stage %q:\n%s", result.Stage, result.RawOutput)
+	}
+
+	want := "module:ok module:ok build:failed test_compile:skipped vet:skipped test:skipped"
+	if got := stageStatuses(result.Stages); got != want {
+		t.Errorf("stages = %s, want %s", got, want)
+	}
+	if len(result.Diagnostics) != 12 {
+		t.Errorf("got %d diagnostics, want 12:\n%s", len(result.Diagnostics), result.CompileOutput)
+	}
+	if result.ExitCode == 0 {
+		t.Errorf("ExitCode = 0 for a failed build")
+	}
+}
+
+func TestCompileStagesTestCompileFailure(t *testing.T) {
+	testCode := "package main\n\nimport \"testing\"\n\nfunc TestTriple(t *testing.T) {\n\t_ = Triple(1)\n}\n"
+
+	result := compileForTest(t, stagesMain, testCode)
+	if result.Stage != StageTestCompile || result.ErrorSubtype != SubtypeUndefined {
+		t.Fatalf("got stage %q subtype %q, want test_compile/undefined:\n%s", result.Stage, result.ErrorSubtype, result.RawOutput)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].File != "main_test.go" || result.Diagnostics[0].Line != 6 {
+		t.Errorf("diagnostics = %+v", result.Diagnostics)
+	}
+}
+
+func TestCompileStagesVetFailure(t *testing.T) {
+	testCode := "package main\n\nimport \"testing\"\n\nfunc TestDouble(t *testing.T) {\n\tt.Logf(\"%d\", \"x\")\n}\n"
+
+	result := compileForTest(t, stagesMain, testCode)
+	if result.Stage != StageVet || result.ErrorSubtype != SubtypeVet {
+		t.Fatalf("got stage %q subtype %q, want vet:\n%s", result.Stage, result.ErrorSubtype, result.RawOutput)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Phase != PhaseVet {
+		t.Errorf("diagnostics = %+v", result.Diagnostics)
+	}
+}
+
+func TestCheckCompileErrorsReportsSections(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	output, err := CheckCompileErrors(stagesMain, stagesTest)
+	if err != nil {
+		t.Fatalf("CheckCompileErrors: %v\n%s", err, output)
+	}
+	if strings.Contains(string(output), "=== TEST OUTPUT ===\n\n") {
+		t.Errorf("test output section is empty:\n%s", output)
+	}
+}
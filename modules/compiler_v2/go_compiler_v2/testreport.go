@@ -0,0 +1,157 @@
+package go_compiler_v2
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// PER-TEST RESULTS FROM go test -json
+// ============================================================================
+
+// TestStatus is the outcome of a single test
+type TestStatus string
+
+const (
+	TestPassed  TestStatus = "pass"
+	TestFailed  TestStatus = "fail"
+	TestSkipped TestStatus = "skip"
+)
+
+// TestCase is the result of one test or subtest
+type TestCase struct {
+	Name    string // Full name, e.g. "TestSum/negative"
+	Parent  string // Enclosing test for subtests, "" for top-level tests
+	Status  TestStatus
+	Elapsed time.Duration
+	Output  string // What the test logged, without the === / --- framing
+}
+
+// TestReport collects every test of a run. The counts only cover leaf
+// tests, so a table test with three cases counts as three, not four.
+type TestReport struct {
+	Tests   []TestCase
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// PassRatio is the fraction of leaf tests that passed, ignoring skipped
+// ones; 0 when nothing ran
+func (r *TestReport) PassRatio() float64 {
+	ran := r.Passed + r.Failed
+	if ran == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(ran)
+}
+
+// FailedTests returns the failed leaf tests
+func (r *TestReport) FailedTests() []TestCase {
+	var failed []TestCase
+	for _, tc := range r.Tests {
+		if tc.Status == TestFailed && !r.hasChildren(tc.Name) {
+			failed = append(failed, tc)
+		}
+	}
+	return failed
+}
+
+func (r *TestReport) hasChildren(name string) bool {
+	for _, tc := range r.Tests {
+		if tc.Parent == name {
+			return true
+		}
+	}
+	return false
+}
+
+// testEvent is a line of go test -json output (see go doc test2json)
+type testEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64 // seconds
+	Output  string
+}
+
+// ParseTestJSON builds a report from go test -json output. It also returns
+// the plain text the run would have printed with -v, for people and for
+// ParseDiagnostics. Lines that aren't JSON events are kept as text.
+func ParseTestJSON(output string) (*TestReport, string) {
+	report := &TestReport{}
+	index := make(map[string]int)
+	var text strings.Builder
+
+	lookup := func(name string) *TestCase {
+		i, ok := index[name]
+		if !ok {
+			parent := ""
+			if slash := strings.LastIndex(name, "/"); slash >= 0 {
+				parent = name[:slash]
+			}
+			i = len(report.Tests)
+			index[name] = i
+			report.Tests = append(report.Tests, TestCase{Name: name, Parent: parent})
+		}
+		return &report.Tests[i]
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		var ev testEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			if line != "" {
+				text.WriteString(line + "\n")
+			}
+			continue
+		}
+
+		text.WriteString(ev.Output)
+		if ev.Test == "" {
+			continue
+		}
+
+		tc := lookup(ev.Test)
+		switch ev.Action {
+		case "output":
+			if !isFrameLine(ev.Output) {
+				tc.Output += ev.Output
+			}
+		case "pass", "fail", "skip":
+			tc.Status = TestStatus(ev.Action)
+			tc.Elapsed = time.Duration(ev.Elapsed * float64(time.Second))
+		}
+	}
+
+	for i := range report.Tests {
+		tc := &report.Tests[i]
+		// A test without a verdict was running when the binary died
+		if tc.Status == "" {
+			tc.Status = TestFailed
+		}
+		if report.hasChildren(tc.Name) {
+			continue
+		}
+		switch tc.Status {
+		case TestPassed:
+			report.Passed++
+		case TestFailed:
+			report.Failed++
+		case TestSkipped:
+			report.Skipped++
+		}
+	}
+
+	return report, text.String()
+}
+
+// isFrameLine reports whether a line is go test's own === / --- framing
+func isFrameLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS", "--- FAIL", "--- SKIP"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
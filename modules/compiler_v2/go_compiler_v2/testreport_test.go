@@ -0,0 +1,116 @@
+package go_compiler_v2
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleTestJSON = `{"Action":"start","Package":"temp_module"}
+{"Action":"run","Package":"temp_module","Test":"TestAdd"}
+{"Action":"output","Package":"temp_module","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"output","Package":"temp_module","Test":"TestAdd","Output":"    main_test.go:7: got 3\n"}
+{"Action":"output","Package":"temp_module","Test":"TestAdd","Output":"        want 4\n"}
+{"Action":"output","Package":"temp_module","Test":"TestAdd","Output":"--- FAIL: TestAdd (0.00s)\n"}
+{"Action":"fail","Package":"temp_module","Test":"TestAdd","Elapsed":0.01}
+{"Action":"run","Package":"temp_module","Test":"TestTable"}
+{"Action":"output","Package":"temp_module","Test":"TestTable","Output":"=== RUN   TestTable\n"}
+{"Action":"run","Package":"temp_module","Test":"TestTable/zero"}
+{"Action":"output","Package":"temp_module","Test":"TestTable/zero","Output":"=== RUN   TestTable/zero\n"}
+{"Action":"run","Package":"temp_module","Test":"TestTable/one"}
+{"Action":"output","Package":"temp_module","Test":"TestTable/one","Output":"=== RUN   TestTable/one\n"}
+{"Action":"output","Package":"temp_module","Test":"TestTable","Output":"--- PASS: TestTable (0.00s)\n"}
+{"Action":"output","Package":"temp_module","Test":"TestTable/zero","Output":"    --- PASS: TestTable/zero (0.00s)\n"}
+{"Action":"pass","Package":"temp_module","Test":"TestTable/zero","Elapsed":0}
+{"Action":"output","Package":"temp_module","Test":"TestTable/one","Output":"    --- PASS: TestTable/one (0.00s)\n"}
+{"Action":"pass","Package":"temp_module","Test":"TestTable/one","Elapsed":0}
+{"Action":"pass","Package":"temp_module","Test":"TestTable","Elapsed":0}
+{"Action":"run","Package":"temp_module","Test":"TestLater"}
+{"Action":"output","Package":"temp_module","Test":"TestLater","Output":"=== RUN   TestLater\n"}
+{"Action":"output","Package":"temp_module","Test":"TestLater","Output":"    main_test.go:20: not yet\n"}
+{"Action":"output","Package":"temp_module","Test":"TestLater","Output":"--- SKIP: TestLater (0.00s)\n"}
+{"Action":"skip","Package":"temp_module","Test":"TestLater","Elapsed":0}
+{"Action":"run","Package":"temp_module","Test":"TestHangs"}
+{"Action":"output","Package":"temp_module","Test":"TestHangs","Output":"=== RUN   TestHangs\n"}
+{"Action":"output","Package":"temp_module","Output":"FAIL\ttemp_module\t0.005s\n"}
+{"Action":"fail","Package":"temp_module","Elapsed":0.005}
+`
+
+func TestParseTestJSON(t *testing.T) {
+	report, text := ParseTestJSON(sampleTestJSON)
+
+	if len(report.Tests) != 6 {
+		t.Fatalf("got %d tests, want 6: %+v", len(report.Tests), report.Tests)
+	}
+
+	add := report.Tests[0]
+	if add.Name != "TestAdd" || add.Status != TestFailed || add.Elapsed != 10*time.Millisecond {
+		t.Errorf("TestAdd = %+v", add)
+	}
+	if add.Output != "    main_test.go:7: got 3\n        want 4\n" {
+		t.Errorf("TestAdd output = %q", add.Output)
+	}
+
+	zero := report.Tests[2]
+	if zero.Name != "TestTable/zero" || zero.Parent != "TestTable" || zero.Status != TestPassed {
+		t.Errorf("subtest = %+v", zero)
+	}
+
+	hangs := report.Tests[5]
+	if hangs.Name != "TestHangs" || hangs.Status != TestFailed {
+		t.Errorf("unfinished test = %+v", hangs)
+	}
+
+	// TestTable itself isn't counted, only its two cases
+	if report.Passed != 2 || report.Failed != 2 || report.Skipped != 1 {
+		t.Errorf("counts = %d passed, %d failed, %d skipped", report.Passed, report.Failed, report.Skipped)
+	}
+	if got := report.PassRatio(); got != 0.5 {
+		t.Errorf("PassRatio = %v, want 0.5", got)
+	}
+
+	var failed []string
+	for _, tc := range report.FailedTests() {
+		failed = append(failed, tc.Name)
+	}
+	if strings.Join(failed, ",") != "TestAdd,TestHangs" {
+		t.Errorf("FailedTests = %v", failed)
+	}
+
+	if !strings.Contains(text, "=== RUN   TestAdd\n    main_test.go:7: got 3\n") || !strings.Contains(text, "FAIL\ttemp_module") {
+		t.Errorf("text output = %q", text)
+	}
+}
+
+func TestParseTestJSONKeepsPlainLines(t *testing.T) {
+	report, text := ParseTestJSON("# temp_module\nsomething went wrong\n")
+	if len(report.Tests) != 0 || report.PassRatio() != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if text != "# temp_module\nsomething went wrong\n" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestCompileReportsTests(t *testing.T) {
+	testCode := stagesTest + `
+func TestHalf(t *testing.T) {
+	if Double(3) != 5 {
+		t.Error("Double(3) != 5")
+	}
+}
+`
+	result := compileForTest(t, stagesMain, testCode)
+	if result.TestReport == nil {
+		t.Fatalf("no test report:\n%s", result.RawOutput)
+	}
+	if result.TestReport.Passed != 1 || result.TestReport.Failed != 1 {
+		t.Errorf("report = %+v", result.TestReport)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Test != "TestHalf" {
+		t.Errorf("diagnostics = %+v", result.Diagnostics)
+	}
+	if strings.Contains(result.TestOutput, `"Action"`) {
+		t.Errorf("TestOutput should be text, got %q", result.TestOutput)
+	}
+}
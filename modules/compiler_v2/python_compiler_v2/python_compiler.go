@@ -0,0 +1,391 @@
+package python_compiler_v2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// PYTHON COMPILER WITH ERROR CLASSIFICATION
+// ============================================================================
+
+const (
+	mainFileName   = "main.py"
+	testFileName   = "test_main.py"
+	runnerFileName = "_run_tests.py"
+)
+
+type PythonCompilerV2 struct{}
+
+func NewPythonCompilerV2() *PythonCompilerV2 {
+	return &PythonCompilerV2{}
+}
+
+// CompilationResultV2 holds detailed compilation output
+type CompilationResultV2 struct {
+	Success       bool
+	ExitCode      int
+	CompileOutput string // py_compile output
+	TestOutput    string // unittest/pytest output
+	RawOutput     string
+	ErrorType     ErrorTypePython
+	ExecutionTime time.Duration
+	CompileErrors []string
+	TestErrors    []string
+}
+
+// ErrorTypePython classifies Python errors
+type ErrorTypePython int
+
+const (
+	ErrorTypePythonUnknown        ErrorTypePython = iota
+	ErrorTypePythonInfrastructure                 // No interpreter, workspace issues
+	ErrorTypePythonSyntax                         // SyntaxError, IndentationError
+	ErrorTypePythonType                           // NameError, TypeError, AttributeError, ImportError
+	ErrorTypePythonLogic                          // AssertionError, failing tests
+	ErrorTypePythonRuntime                        // Any other exception, hangs
+	ErrorTypePythonSuccess                        // No error
+)
+
+// testRunner runs unittest TestCases and bare pytest-style test_* functions
+// from test_main.py without needing pytest installed. Failures are printed in
+// unittest's FAIL:/ERROR: format so there is a single format to parse.
+const testRunner = `import inspect
+import sys
+import traceback
+import unittest
+
+SEPARATOR = "=" * 70
+
+try:
+    import test_main
+except BaseException:
+    traceback.print_exc()
+    sys.exit(1)
+
+suite = unittest.defaultTestLoader.loadTestsFromModule(test_main)
+result = unittest.TextTestRunner(stream=sys.stdout, verbosity=2).run(suite)
+failed = not result.wasSuccessful()
+
+for name, fn in inspect.getmembers(test_main, inspect.isfunction):
+    if not name.startswith("test") or fn.__module__ != "test_main":
+        continue
+    if inspect.signature(fn).parameters:
+        continue
+    try:
+        fn()
+        print(name + " ... ok")
+    except AssertionError:
+        failed = True
+        print(SEPARATOR)
+        print("FAIL: " + name)
+        print("-" * 70)
+        traceback.print_exc(file=sys.stdout)
+    except BaseException:
+        failed = True
+        print(SEPARATOR)
+        print("ERROR: " + name)
+        print("-" * 70)
+        traceback.print_exc(file=sys.stdout)
+
+sys.exit(1 if failed else 0)
+`
+
+// Compile byte-compiles main.py and test_main.py in an isolated workspace and
+// then runs the tests. Tests that import pytest are run with pytest when it
+// is installed; everything else goes through testRunner.
+func (pc *PythonCompilerV2) Compile(ctx context.Context, mainCode, testCode string) (*CompilationResultV2, error) {
+	startTime := time.Now()
+	result := &CompilationResultV2{}
+	defer func() { result.ExecutionTime = time.Since(startTime) }()
+
+	python, err := findPython()
+	if err != nil {
+		return infrastructureResult(result, err.Error()), nil
+	}
+
+	// Create fresh temp directory
+	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("python_compile_%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return infrastructureResult(result, fmt.Sprintf("Failed to create temp directory: %v", err)), nil
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{mainFileName: mainCode, runnerFileName: testRunner}
+	hasTests := strings.TrimSpace(testCode) != ""
+	if hasTests {
+		files[testFileName] = testCode
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			return infrastructureResult(result, fmt.Sprintf("Failed to write %s: %v", name, err)), nil
+		}
+	}
+
+	// Stage 1: byte-compile check
+	compileArgs := []string{"-m", "py_compile", mainFileName}
+	if hasTests {
+		compileArgs = append(compileArgs, testFileName)
+	}
+	output, exitCode, err := runPython(ctx, tempDir, python, compileArgs...)
+	result.CompileOutput = output
+	result.RawOutput = output
+	if ctx.Err() != nil {
+		return infrastructureResult(result, "Compilation exceeded timeout"), nil
+	}
+	if err != nil && exitCode < 0 {
+		return infrastructureResult(result, fmt.Sprintf("Failed to run %s: %v", python, err)), nil
+	}
+	if exitCode != 0 {
+		result.ExitCode = exitCode
+		result.CompileErrors = parsePythonErrors(output, tempDir)
+		result.ErrorType = classifyPythonError(output, false)
+		return result, nil
+	}
+
+	if !hasTests {
+		result.Success = true
+		result.ErrorType = ErrorTypePythonSuccess
+		return result, nil
+	}
+
+	// Stage 2: run the tests
+	testArgs := []string{runnerFileName}
+	if usesPytest(testCode) {
+		if pytestInstalled(ctx, tempDir, python) {
+			testArgs = []string{"-m", "pytest", "-q", "--tb=native", "-p", "no:cacheprovider", testFileName}
+		} else {
+			result.ExitCode = 1
+			result.ErrorType = ErrorTypePythonType
+			result.TestErrors = []string{"pytest is not installed; write the tests with unittest instead"}
+			return result, nil
+		}
+	}
+
+	output, exitCode, err = runPython(ctx, tempDir, python, testArgs...)
+	result.TestOutput = output
+	result.RawOutput = result.CompileOutput + "\n" + output
+	if ctx.Err() != nil {
+		// The byte-compile step finished, so a hang here is the code's fault
+		result.ExitCode = 1
+		result.ErrorType = ErrorTypePythonRuntime
+		result.TestErrors = []string{"Tests exceeded timeout (possible infinite loop)"}
+		return result, nil
+	}
+	if err != nil && exitCode < 0 {
+		return infrastructureResult(result, fmt.Sprintf("Failed to run %s: %v", python, err)), nil
+	}
+	if exitCode == 0 {
+		result.Success = true
+		result.ErrorType = ErrorTypePythonSuccess
+		return result, nil
+	}
+
+	result.ExitCode = exitCode
+	result.TestErrors = parsePythonErrors(output, tempDir)
+	result.ErrorType = classifyPythonError(output, true)
+	return result, nil
+}
+
+func infrastructureResult(result *CompilationResultV2, message string) *CompilationResultV2 {
+	result.ErrorType = ErrorTypePythonInfrastructure
+	result.ExitCode = 1
+	result.RawOutput = strings.TrimSpace(result.RawOutput + "\n" + message)
+	result.CompileErrors = append(result.CompileErrors, message)
+	return result
+}
+
+// findPython returns the first Python 3 interpreter on PATH
+func findPython() (string, error) {
+	for _, name := range []string{"python3", "python"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no python interpreter found on PATH")
+}
+
+// runPython runs the interpreter in dir and returns its combined output and
+// exit code. The exit code is -1 if the process could not be started.
+func runPython(ctx context.Context, dir, python string, args ...string) (string, int, error) {
+	cmd := exec.CommandContext(ctx, python, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PYTHONDONTWRITEBYTECODE=1", "PYTHONUNBUFFERED=1")
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err == nil {
+		return output.String(), 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		// Killed by a signal reports -1; keep that distinct from "not started"
+		if exitErr.ExitCode() < 0 {
+			return output.String() + "\n" + exitErr.Error(), 1, err
+		}
+		return output.String(), exitErr.ExitCode(), err
+	}
+	return output.String(), -1, err
+}
+
+var pytestImportPattern = regexp.MustCompile(`(?m)^\s*(import pytest|from pytest import)`)
+
+func usesPytest(testCode string) bool {
+	return pytestImportPattern.MatchString(testCode)
+}
+
+func pytestInstalled(ctx context.Context, dir, python string) bool {
+	_, exitCode, _ := runPython(ctx, dir, python, "-c", "import pytest")
+	return exitCode == 0
+}
+
+// ============================================================================
+// ERROR PARSING & CLASSIFICATION
+// ============================================================================
+
+var (
+	// File "/tmp/python_compile_1/main.py", line 5, in add
+	framePattern = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)`)
+	// NameError: name 'x' is not defined
+	exceptionPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt)|AssertionError|StopIteration)(?::\s*(.*))?$`)
+	// FAIL: test_add (test_main.TestAdd.test_add) / ERROR: test_add
+	testHeaderPattern = regexp.MustCompile(`^(?:FAIL|ERROR): (\S+)`)
+	// ____ test_add ____ (pytest)
+	pytestHeaderPattern = regexp.MustCompile(`^_{3,} (\S+) _{3,}$`)
+)
+
+// parsePythonErrors turns tracebacks into one line per exception in the form
+// "[test: ]file:line: ExceptionType: message", pointing at the deepest frame
+// that belongs to the generated files.
+func parsePythonErrors(output, workspace string) []string {
+	var errors []string
+	var currentTest, location string
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimRight(line, " \r")
+
+		if m := testHeaderPattern.FindStringSubmatch(trimmed); m != nil {
+			currentTest, location = m[1], ""
+			continue
+		}
+		if m := pytestHeaderPattern.FindStringSubmatch(trimmed); m != nil {
+			currentTest, location = m[1], ""
+			continue
+		}
+
+		if m := framePattern.FindStringSubmatch(trimmed); m != nil {
+			file := filepath.Base(m[1])
+			if isGeneratedFile(m[1], workspace) {
+				location = file + ":" + m[2]
+			}
+			continue
+		}
+
+		// Exception lines are never indented; source lines are
+		if strings.HasPrefix(trimmed, " ") {
+			continue
+		}
+		if m := exceptionPattern.FindStringSubmatch(trimmed); m != nil {
+			entry := trimmed
+			if location != "" {
+				entry = location + ": " + entry
+			}
+			if currentTest != "" {
+				entry = currentTest + ": " + entry
+			}
+			errors = append(errors, entry)
+			location = ""
+		}
+	}
+
+	if len(errors) == 0 && strings.TrimSpace(output) != "" {
+		errors = append(errors, lastNonEmptyLine(output))
+	}
+
+	return errors
+}
+
+// isGeneratedFile reports whether a traceback frame points at main.py or
+// test_main.py rather than the runner or the standard library
+func isGeneratedFile(path, workspace string) bool {
+	base := filepath.Base(path)
+	if base != mainFileName && base != testFileName {
+		return false
+	}
+	return !filepath.IsAbs(path) || strings.HasPrefix(path, workspace)
+}
+
+func lastNonEmptyLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// classifyPythonError picks the most severe exception in the output.
+// inTests is false for the byte-compile stage.
+func classifyPythonError(output string, inTests bool) ErrorTypePython {
+	seen := map[ErrorTypePython]bool{}
+
+	for _, line := range strings.Split(output, "\n") {
+		m := exceptionPattern.FindStringSubmatch(strings.TrimRight(line, " \r"))
+		if m == nil {
+			continue
+		}
+		seen[exceptionErrorType(m[1])] = true
+	}
+
+	for _, errorType := range []ErrorTypePython{ErrorTypePythonSyntax, ErrorTypePythonType, ErrorTypePythonRuntime, ErrorTypePythonLogic} {
+		if seen[errorType] {
+			return errorType
+		}
+	}
+
+	if !inTests {
+		return ErrorTypePythonSyntax
+	}
+	if strings.Contains(output, "FAIL") {
+		return ErrorTypePythonLogic
+	}
+	return ErrorTypePythonUnknown
+}
+
+// exceptionErrorType maps a Python exception name onto an error class
+func exceptionErrorType(name string) ErrorTypePython {
+	name = name[strings.LastIndex(name, ".")+1:]
+	switch name {
+	case "SyntaxError", "IndentationError", "TabError":
+		return ErrorTypePythonSyntax
+	case "NameError", "UnboundLocalError", "TypeError", "AttributeError", "ImportError", "ModuleNotFoundError":
+		return ErrorTypePythonType
+	case "AssertionError":
+		return ErrorTypePythonLogic
+	default:
+		return ErrorTypePythonRuntime
+	}
+}
+
+// ClassifyPythonError classifies a failed compilation from its raw output.
+// inTests reports whether the failure came from the test run rather than
+// the byte-compile check.
+func ClassifyPythonError(output string, inTests bool) ErrorTypePython {
+	return classifyPythonError(output, inTests)
+}
+
+// Version reports the interpreter in use, e.g. "Python 3.12.3"
+func Version(ctx context.Context) (string, error) {
+	python, err := findPython()
+	if err != nil {
+		return "", err
+	}
+	// Python 2 printed its version to stderr
+	out, err := exec.CommandContext(ctx, python, "--version").CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
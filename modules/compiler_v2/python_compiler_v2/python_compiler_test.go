@@ -0,0 +1,144 @@
+package python_compiler_v2
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const addMain = `def add(a, b):
+    return a + b
+
+
+if __name__ == "__main__":
+    print(add(2, 3))
+`
+
+func TestCompile(t *testing.T) {
+	if _, err := findPython(); err != nil {
+		t.Skip("python is not installed")
+	}
+
+	tests := []struct {
+		name          string
+		mainCode      string
+		testCode      string
+		wantSuccess   bool
+		wantErrorType ErrorTypePython
+	}{
+		{
+			name:     "unittest passes",
+			mainCode: addMain,
+			testCode: `import unittest
+from main import add
+
+
+class TestAdd(unittest.TestCase):
+    def test_add(self):
+        self.assertEqual(add(2, 3), 5)
+`,
+			wantSuccess:   true,
+			wantErrorType: ErrorTypePythonSuccess,
+		},
+		{
+			name:     "bare test functions pass",
+			mainCode: addMain,
+			testCode: `from main import add
+
+
+def test_add():
+    assert add(2, 3) == 5
+`,
+			wantSuccess:   true,
+			wantErrorType: ErrorTypePythonSuccess,
+		},
+		{
+			name:          "syntax error",
+			mainCode:      "def add(a, b:\n    return a + b\n",
+			testCode:      "from main import add\n",
+			wantErrorType: ErrorTypePythonSyntax,
+		},
+		{
+			name:     "name error",
+			mainCode: "def add(a, b):\n    return a + c\n",
+			testCode: `from main import add
+
+
+def test_add():
+    assert add(2, 3) == 5
+`,
+			wantErrorType: ErrorTypePythonType,
+		},
+		{
+			name:     "assertion failure",
+			mainCode: "def add(a, b):\n    return a - b\n",
+			testCode: `import unittest
+from main import add
+
+
+class TestAdd(unittest.TestCase):
+    def test_add(self):
+        self.assertEqual(add(2, 3), 5)
+`,
+			wantErrorType: ErrorTypePythonLogic,
+		},
+		{
+			name:     "runtime error",
+			mainCode: "def div(a, b):\n    return a / b\n",
+			testCode: `from main import div
+
+
+def test_div():
+    assert div(1, 0) == 0
+`,
+			wantErrorType: ErrorTypePythonRuntime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			result, err := NewPythonCompilerV2().Compile(ctx, test.mainCode, test.testCode)
+			if err != nil {
+				t.Fatalf("Compile returned error: %v", err)
+			}
+			if result.Success != test.wantSuccess || result.ErrorType != test.wantErrorType {
+				t.Errorf("got success=%v errorType=%v, want success=%v errorType=%v\noutput:\n%s",
+					result.Success, result.ErrorType, test.wantSuccess, test.wantErrorType, result.RawOutput)
+			}
+			if !test.wantSuccess && len(result.CompileErrors)+len(result.TestErrors) == 0 {
+				t.Errorf("expected parsed errors, got none\noutput:\n%s", result.RawOutput)
+			}
+		})
+	}
+}
+
+func TestParsePythonErrors(t *testing.T) {
+	output := `test_add (test_main.TestAdd.test_add) ... FAIL
+
+======================================================================
+FAIL: test_add (test_main.TestAdd.test_add)
+----------------------------------------------------------------------
+Traceback (most recent call last):
+  File "/tmp/python_compile_1/test_main.py", line 7, in test_add
+    self.assertEqual(add(2, 3), 5)
+AssertionError: -1 != 5
+`
+	errors := parsePythonErrors(output, "/tmp/python_compile_1")
+	want := "test_add: test_main.py:7: AssertionError: -1 != 5"
+	if len(errors) != 1 || errors[0] != want {
+		t.Errorf("parsePythonErrors = %q, want [%q]", errors, want)
+	}
+}
+
+func TestFindPythonMatchesPath(t *testing.T) {
+	_, lookErr := exec.LookPath("python3")
+	path, err := findPython()
+	if lookErr == nil && (err != nil || !strings.Contains(path, "python")) {
+		t.Errorf("findPython() = %q, %v", path, err)
+	}
+}
@@ -80,3 +80,19 @@ func TestExtraction(t *testing.T) {
 		})
 	}
 }
+
+func TestLanguageTagStrategyTags(t *testing.T) {
+	response := "```c++\nint add(int a, int b) { return a + b; }\n```\n\n```c++\nvoid test_add() {}\n```"
+
+	if _, _, err := (LanguageTagStrategy{}).Extract(response); err == nil {
+		t.Error("Expected default strategy to only accept go blocks")
+	}
+
+	main, test, err := LanguageTagStrategy{Tags: []string{"cpp", "c++"}}.Extract(response)
+	if err != nil {
+		t.Fatalf("Expected c++ blocks to be extracted, got %v", err)
+	}
+	if main != "int add(int a, int b) { return a + b; }" || test != "void test_add() {}" {
+		t.Errorf("Unexpected extraction: main=%q test=%q", main, test)
+	}
+}
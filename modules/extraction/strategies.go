@@ -125,19 +125,32 @@ func (s TestMarkerStrategy) Extract(response string) (main, test string, err err
 // STRATEGY 4: Code Block With Language Tags
 // ============================================================================
 
-type LanguageTagStrategy struct{}
+// LanguageTagStrategy only accepts code blocks tagged with one of Tags.
+// The zero value matches ```go blocks.
+type LanguageTagStrategy struct {
+	Tags []string
+}
 
 func (s LanguageTagStrategy) Name() string {
 	return "language_tag_split"
 }
 
 func (s LanguageTagStrategy) Extract(response string) (main, test string, err error) {
-	// Match: ```go\ncode\n``` and capture multiple blocks
-	goBlockPattern := regexp.MustCompile("(?s)```go\\n(.*?)```")
-	matches := goBlockPattern.FindAllStringSubmatch(response, -1)
+	tags := s.Tags
+	if len(tags) == 0 {
+		tags = []string{"go"}
+	}
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = regexp.QuoteMeta(tag)
+	}
+
+	// Match: ```<tag>\ncode\n``` and capture multiple blocks
+	blockPattern := regexp.MustCompile("(?s)```(?:" + strings.Join(quoted, "|") + ")\\n(.*?)```")
+	matches := blockPattern.FindAllStringSubmatch(response, -1)
 
 	if len(matches) < 2 {
-		return "", "", fmt.Errorf("%s: expected at least 2 'go' code blocks, found %d", s.Name(), len(matches))
+		return "", "", fmt.Errorf("%s: expected at least 2 '%s' code blocks, found %d", s.Name(), tags[0], len(matches))
 	}
 
 	mainCode := strings.TrimSpace(matches[0][1])
@@ -201,6 +214,13 @@ type Extractor struct {
 	strategies []ExtractionStrategy
 }
 
+// NewExtractorWithStrategies builds an extractor that tries the given
+// strategies in order
+func NewExtractorWithStrategies(strategies ...ExtractionStrategy) *Extractor {
+	return &Extractor{strategies: strategies}
+}
+
+// NewExtractor returns the default extractor, tuned for Go responses
 func NewExtractor() *Extractor {
 	return &Extractor{
 		strategies: []ExtractionStrategy{
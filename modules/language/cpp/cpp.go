@@ -0,0 +1,104 @@
+package cpp
+
+import (
+	"context"
+	"llama/modules/compiler_v2/cpp_compiler_v2"
+	"llama/modules/extraction"
+	"llama/modules/language"
+	"strings"
+)
+
+// ============================================================================
+// C++ LANGUAGE BACKEND
+// ============================================================================
+
+const formatInstructions = `
+IMPORTANT: Generate C++ code in this exact format:
+- Two code blocks separated by a blank line
+- First block: main.cpp with function implementations and main()
+- Second block: test.cpp with void test_*() functions using assert from <cassert>
+- The test block can call functions from main.cpp directly; do not define main() in it
+- Include necessary #include statements
+- Provide code only, no explanations
+`
+
+func init() {
+	language.Register(New())
+}
+
+// Cpp builds and tests code with CppCompilerV2 using g++ or clang++
+type Cpp struct{}
+
+func New() *Cpp {
+	return &Cpp{}
+}
+
+func (c *Cpp) Name() string {
+	return "cpp"
+}
+
+func (c *Cpp) FormatInstructions() string {
+	return formatInstructions
+}
+
+func (c *Cpp) Extractor() *extraction.Extractor {
+	return extraction.NewExtractorWithStrategies(
+		extraction.StandardFormatStrategy{},
+		extraction.LanguageTagStrategy{Tags: []string{"cpp", "c++", "cc"}},
+		extraction.SingleFileStrategy{},
+	)
+}
+
+func (c *Cpp) FileNames() (string, string) {
+	return "main.cpp", "test.cpp"
+}
+
+func (c *Cpp) Compile(ctx context.Context, mainCode, testCode string) (*language.CompilationResult, error) {
+	cppResult, err := cpp_compiler_v2.NewCppCompilerV2().Compile(ctx, mainCode, testCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &language.CompilationResult{
+		Success:       cppResult.Success,
+		ExitCode:      cppResult.ExitCode,
+		CompileErrors: cppResult.CompileErrors,
+		TestErrors:    cppResult.TestErrors,
+		Output:        cppResult.RawOutput,
+		ErrorType:     mapErrorType(cppResult.ErrorType),
+		ExecutionTime: cppResult.ExecutionTime,
+	}, nil
+}
+
+// ToolchainVersion implements language.Versioner
+func (c *Cpp) ToolchainVersion(ctx context.Context) (string, error) {
+	return cpp_compiler_v2.Version(ctx)
+}
+
+func (c *Cpp) ClassifyError(result *language.CompilationResult) language.ErrorType {
+	if result.Success {
+		return language.ErrorTypeSuccess
+	}
+	output := result.Output + "\n" + strings.Join(result.TestErrors, "\n")
+	return mapErrorType(cpp_compiler_v2.ClassifyCppError(result.CompileErrors, output))
+}
+
+// mapErrorType translates the C++ compiler's error classes to language.ErrorType
+func mapErrorType(e cpp_compiler_v2.ErrorTypeCpp) language.ErrorType {
+	switch e {
+	case cpp_compiler_v2.ErrorTypeCppInfrastructure:
+		return language.ErrorTypeInfrastructure
+	case cpp_compiler_v2.ErrorTypeCppSyntax:
+		return language.ErrorTypeSyntax
+	case cpp_compiler_v2.ErrorTypeCppType:
+		return language.ErrorTypeType
+	case cpp_compiler_v2.ErrorTypeCppLogic:
+		return language.ErrorTypeLogic
+	case cpp_compiler_v2.ErrorTypeCppRuntime:
+		return language.ErrorTypeRuntime
+	case cpp_compiler_v2.ErrorTypeCppSuccess:
+		return language.ErrorTypeSuccess
+	default:
+		return language.ErrorTypeUnknown
+	}
+}
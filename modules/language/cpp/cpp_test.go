@@ -0,0 +1,30 @@
+package cpp
+
+import (
+	"context"
+	"llama/modules/language"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	mainCode := "int add(int a, int b) {\n    return a + b;\n}\n\nint main() { return 0; }\n"
+	testCode := "#include <cassert>\n\nvoid test_add() {\n    assert(add(2, 3) == 6);\n}\n"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := New().Compile(ctx, mainCode, testCode)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if result.ErrorType == language.ErrorTypeInfrastructure {
+		t.Skipf("C++ compiler unavailable: %s", result.Output)
+	}
+	if result.Success || result.ErrorType != language.ErrorTypeLogic {
+		t.Errorf("expected logic error, got success=%v errorType=%s output:\n%s", result.Success, result.ErrorType, result.Output)
+	}
+	if got := New().ClassifyError(result); got != result.ErrorType {
+		t.Errorf("ClassifyError = %s, want %s", got, result.ErrorType)
+	}
+}
@@ -0,0 +1,266 @@
+package golang
+
+import (
+	"context"
+	"llama/modules/compiler_v2/go_compiler_v2"
+	"llama/modules/extraction"
+	"llama/modules/language"
+)
+
+// ============================================================================
+// GO LANGUAGE BACKEND
+// ============================================================================
+
+const formatInstructions = `
+IMPORTANT: Generate Go code in this exact format:
+- Two code blocks separated by a blank line
+- First block: package main with main() function and helper functions
+- Second block: package main with import "testing" and Test* functions
+- Use ONLY "package main" in both blocks
+- Provide code only, no explanations
+`
+
+func init() {
+	language.Register(New())
+}
+
+// Go builds and tests code with GoCompilerV2
+type Go struct{}
+
+func New() *Go {
+	return &Go{}
+}
+
+func (g *Go) Name() string {
+	return "go"
+}
+
+func (g *Go) FormatInstructions() string {
+	return formatInstructions
+}
+
+func (g *Go) Extractor() *extraction.Extractor {
+	return extraction.NewExtractor()
+}
+
+func (g *Go) FileNames() (string, string) {
+	return "main.go", "main_test.go"
+}
+
+// Compile runs the code through GoCompilerV2, which uses a fresh temp
+// directory per call and kills the toolchain when ctx expires
+func (g *Go) Compile(ctx context.Context, mainCode, testCode string) (*language.CompilationResult, error) {
+	return g.CompileWithPhases(ctx, mainCode, testCode, func(string) {})
+}
+
+// CompileWithPhases implements language.PhaseReporter
+func (g *Go) CompileWithPhases(ctx context.Context, mainCode, testCode string, onPhase func(string)) (*language.CompilationResult, error) {
+	gc := go_compiler_v2.NewGoCompilerV2()
+	phase := ""
+	gc.OnStage = func(stage go_compiler_v2.Stage) {
+		next := language.PhaseCompiling
+		if stage == go_compiler_v2.StageTest {
+			next = language.PhaseTesting
+		}
+		if next != phase {
+			phase = next
+			onPhase(phase)
+		}
+	}
+
+	goResult, err := gc.Compile(ctx, mainCode, testCode)
+	if err != nil {
+		return nil, err
+	}
+	return mapResult(ctx, goResult), nil
+}
+
+// ToolchainVersion implements language.Versioner
+func (g *Go) ToolchainVersion(ctx context.Context) (string, error) {
+	return go_compiler_v2.Version(ctx)
+}
+
+// RecoveryActions implements language.Recoverer
+func (g *Go) RecoveryActions(result *language.CompilationResult) []string {
+	var actions []string
+	for _, action := range go_compiler_v2.RecoveryActions(&go_compiler_v2.CompilationResultV2{
+		Success:   result.Success,
+		ErrorType: unmapErrorType(result.ErrorType),
+		Stage:     go_compiler_v2.Stage(result.Stage),
+	}) {
+		actions = append(actions, string(action))
+	}
+	return actions
+}
+
+// Recover implements language.Recoverer
+func (g *Go) Recover(ctx context.Context, action, mainCode, testCode string) (*language.CompilationResult, error) {
+	goResult, err := go_compiler_v2.NewGoCompilerV2().Recover(ctx, go_compiler_v2.RecoveryAction(action), mainCode, testCode)
+	if err != nil {
+		return nil, err
+	}
+	return mapResult(ctx, goResult), nil
+}
+
+// AutoFix implements language.AutoFixer
+func (g *Go) AutoFix(mainCode, testCode string) (string, string, []language.AutoFix) {
+	mainCode, testCode, fixes := go_compiler_v2.AutoFix(mainCode, testCode)
+	var out []language.AutoFix
+	for _, f := range fixes {
+		out = append(out, language.AutoFix{Name: f.Name, File: f.File, Detail: f.Detail})
+	}
+	return mainCode, testCode, out
+}
+
+// mapResult converts a GoCompilerV2 result to language.CompilationResult
+func mapResult(ctx context.Context, goResult *go_compiler_v2.CompilationResultV2) *language.CompilationResult {
+	result := &language.CompilationResult{
+		Success:       goResult.Success,
+		ExitCode:      goResult.ExitCode,
+		CompileErrors: goResult.CompileErrors,
+		TestErrors:    goResult.TestErrors,
+		Diagnostics:   mapDiagnostics(goResult.Diagnostics),
+		Output:        goResult.RawOutput,
+		ErrorType:     mapErrorType(goResult.ErrorType),
+		ErrorSubtype:  string(goResult.ErrorSubtype),
+		Stage:         string(goResult.Stage),
+		Stages:        mapStages(goResult.Stages),
+		Tests:         mapTestReport(goResult.TestReport),
+		ExecutionTime: goResult.ExecutionTime,
+	}
+
+	// A run cut short by the job deadline or a user cancel is not the
+	// generated code's fault, so don't let it feed back into the LLM.
+	if ctx.Err() != nil && !result.Success {
+		result.ErrorType = language.ErrorTypeInfrastructure
+		result.ErrorSubtype = ""
+	}
+
+	return result
+}
+
+func (g *Go) ClassifyError(result *language.CompilationResult) language.ErrorType {
+	if result.Success {
+		return language.ErrorTypeSuccess
+	}
+	errorType, _ := go_compiler_v2.ClassifyGoError(go_compiler_v2.Stage(result.Stage), unmapDiagnostics(result.Diagnostics))
+	return mapErrorType(errorType)
+}
+
+// mapDiagnostics converts the Go compiler's diagnostics to language.Diagnostic
+func mapDiagnostics(diags []go_compiler_v2.Diagnostic) []language.Diagnostic {
+	if len(diags) == 0 {
+		return nil
+	}
+	out := make([]language.Diagnostic, len(diags))
+	for i, d := range diags {
+		out[i] = language.Diagnostic{
+			File:    d.File,
+			Line:    d.Line,
+			Column:  d.Column,
+			Message: d.Message,
+			Phase:   string(d.Phase),
+			Test:    d.Test,
+			Source:  d.Source,
+		}
+	}
+	return out
+}
+
+// mapStages converts the Go compiler's stage results to language.StageResult
+func mapStages(stages []go_compiler_v2.StageResult) []language.StageResult {
+	out := make([]language.StageResult, len(stages))
+	for i, sr := range stages {
+		out[i] = language.StageResult{
+			Name:       string(sr.Stage),
+			Command:    sr.Command,
+			Status:     string(sr.Status),
+			ExitCode:   sr.ExitCode,
+			DurationMs: sr.Duration.Milliseconds(),
+			Output:     sr.Output,
+		}
+	}
+	return out
+}
+
+// mapTestReport converts the Go compiler's test report to language.TestReport
+func mapTestReport(report *go_compiler_v2.TestReport) *language.TestReport {
+	if report == nil {
+		return nil
+	}
+
+	out := &language.TestReport{
+		Tests:     make([]language.TestCase, len(report.Tests)),
+		Passed:    report.Passed,
+		Failed:    report.Failed,
+		Skipped:   report.Skipped,
+		PassRatio: report.PassRatio(),
+	}
+	for i, tc := range report.Tests {
+		out.Tests[i] = language.TestCase{
+			Name:      tc.Name,
+			Parent:    tc.Parent,
+			Status:    string(tc.Status),
+			ElapsedMs: tc.Elapsed.Milliseconds(),
+			Output:    tc.Output,
+		}
+	}
+	return out
+}
+
+// unmapDiagnostics is the inverse of mapDiagnostics
+func unmapDiagnostics(diags []language.Diagnostic) []go_compiler_v2.Diagnostic {
+	out := make([]go_compiler_v2.Diagnostic, len(diags))
+	for i, d := range diags {
+		out[i] = go_compiler_v2.Diagnostic{
+			File:    d.File,
+			Line:    d.Line,
+			Column:  d.Column,
+			Message: d.Message,
+			Phase:   go_compiler_v2.DiagnosticPhase(d.Phase),
+			Test:    d.Test,
+			Source:  d.Source,
+		}
+	}
+	return out
+}
+
+// mapErrorType translates the Go compiler's error classes to language.ErrorType
+func mapErrorType(e go_compiler_v2.ErrorTypeGo) language.ErrorType {
+	switch e {
+	case go_compiler_v2.ErrorTypeGoInfrastructure:
+		return language.ErrorTypeInfrastructure
+	case go_compiler_v2.ErrorTypeGoSyntax:
+		return language.ErrorTypeSyntax
+	case go_compiler_v2.ErrorTypeGoType:
+		return language.ErrorTypeType
+	case go_compiler_v2.ErrorTypeGoLogic:
+		return language.ErrorTypeLogic
+	case go_compiler_v2.ErrorTypeGoRuntime:
+		return language.ErrorTypeRuntime
+	case go_compiler_v2.ErrorTypeGoSuccess:
+		return language.ErrorTypeSuccess
+	default:
+		return language.ErrorTypeUnknown
+	}
+}
+
+// unmapErrorType is the inverse of mapErrorType
+func unmapErrorType(e language.ErrorType) go_compiler_v2.ErrorTypeGo {
+	switch e {
+	case language.ErrorTypeInfrastructure:
+		return go_compiler_v2.ErrorTypeGoInfrastructure
+	case language.ErrorTypeSyntax:
+		return go_compiler_v2.ErrorTypeGoSyntax
+	case language.ErrorTypeType:
+		return go_compiler_v2.ErrorTypeGoType
+	case language.ErrorTypeLogic:
+		return go_compiler_v2.ErrorTypeGoLogic
+	case language.ErrorTypeRuntime:
+		return go_compiler_v2.ErrorTypeGoRuntime
+	case language.ErrorTypeSuccess:
+		return go_compiler_v2.ErrorTypeGoSuccess
+	default:
+		return go_compiler_v2.ErrorTypeGoUnknown
+	}
+}
@@ -0,0 +1,173 @@
+package golang
+
+import (
+	"context"
+	"llama/modules/compiler_v2/go_compiler_v2"
+	"llama/modules/language"
+	"testing"
+	"time"
+)
+
+const sumMain = `package main
+
+import "fmt"
+
+func Sum(a, b int) int {
+	return a + b
+}
+
+func main() {
+	fmt.Println(Sum(2, 3))
+}`
+
+const sumTest = `package main
+
+import "testing"
+
+func TestSum(t *testing.T) {
+	if got := Sum(2, 3); got != 5 {
+		t.Errorf("Sum(2, 3) = %d, want 5", got)
+	}
+}`
+
+func TestMapErrorType(t *testing.T) {
+	tests := []struct {
+		in   go_compiler_v2.ErrorTypeGo
+		want language.ErrorType
+	}{
+		{go_compiler_v2.ErrorTypeGoInfrastructure, language.ErrorTypeInfrastructure},
+		{go_compiler_v2.ErrorTypeGoSyntax, language.ErrorTypeSyntax},
+		{go_compiler_v2.ErrorTypeGoType, language.ErrorTypeType},
+		{go_compiler_v2.ErrorTypeGoLogic, language.ErrorTypeLogic},
+		{go_compiler_v2.ErrorTypeGoRuntime, language.ErrorTypeRuntime},
+		{go_compiler_v2.ErrorTypeGoSuccess, language.ErrorTypeSuccess},
+		{go_compiler_v2.ErrorTypeGoUnknown, language.ErrorTypeUnknown},
+	}
+
+	for _, test := range tests {
+		if got := mapErrorType(test.in); got != test.want {
+			t.Errorf("mapErrorType(%v) = %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := New().Compile(ctx, sumMain, sumTest)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if !result.Success || result.ErrorType != language.ErrorTypeSuccess {
+		t.Errorf("expected success, got errorType=%s output:\n%s", result.ErrorType, result.Output)
+	}
+}
+
+func TestCompileCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := New().Compile(ctx, sumMain, sumTest)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if result.Success || result.ErrorType != language.ErrorTypeInfrastructure {
+		t.Errorf("expected infrastructure error for cancelled context, got success=%v errorType=%s", result.Success, result.ErrorType)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := language.Lookup("go"); err != nil {
+		t.Errorf("go backend not registered: %v", err)
+	}
+}
+
+func TestCompileDiagnostics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	broken := "package main\n\nfunc Sum(a, b int) int {\n\treturn a + c\n}\n\nfunc main() {}\n"
+	result, err := New().Compile(ctx, broken, sumTest)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if len(result.Diagnostics) == 0 {
+		t.Fatalf("expected diagnostics, got none; output:\n%s", result.Output)
+	}
+
+	d := result.Diagnostics[0]
+	if d.Location() != "main.go:4:13" || d.Phase != "build" || d.Source != "return a + c" {
+		t.Errorf("unexpected diagnostic %+v", d)
+	}
+}
+
+func TestClassifyErrorUsesStage(t *testing.T) {
+	result := &language.CompilationResult{
+		Stage: "test",
+		Diagnostics: []language.Diagnostic{
+			{File: "main_test.go", Line: 8, Message: "expected type int, got unexpected value", Phase: "test", Test: "TestSum"},
+		},
+	}
+	if got := New().ClassifyError(result); got != language.ErrorTypeLogic {
+		t.Errorf("ClassifyError = %s, want logic", got)
+	}
+}
+
+func TestCompileReportsStages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := New().Compile(ctx, sumMain, sumTest)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if len(result.Stages) == 0 {
+		t.Fatal("expected stage results")
+	}
+	for _, stage := range result.Stages {
+		if stage.Status != "ok" {
+			t.Errorf("stage %s status = %s\n%s", stage.Name, stage.Status, stage.Output)
+		}
+	}
+	if last := result.Stages[len(result.Stages)-1]; last.Name != "test" {
+		t.Errorf("last stage = %s, want test", last.Name)
+	}
+	if result.Tests == nil || result.Tests.Passed != 1 || result.Tests.PassRatio != 1 {
+		t.Errorf("test report = %+v", result.Tests)
+	}
+}
+
+func TestRecoveryActions(t *testing.T) {
+	var recoverer language.Recoverer = New()
+
+	result := &language.CompilationResult{ErrorType: language.ErrorTypeInfrastructure, Stage: "module"}
+	got := recoverer.RecoveryActions(result)
+	if len(got) != 3 || got[1] != "offline_tidy" {
+		t.Errorf("RecoveryActions for a module failure = %v", got)
+	}
+
+	result = &language.CompilationResult{ErrorType: language.ErrorTypeType, Stage: "build"}
+	if got := recoverer.RecoveryActions(result); len(got) != 0 {
+		t.Errorf("RecoveryActions for a type error = %v, want none", got)
+	}
+}
+
+func TestAutoFix(t *testing.T) {
+	var fixer language.AutoFixer = New()
+
+	mainCode, _, fixes := fixer.AutoFix("package main\n\nfunc main() {\n\tfmt.Println(1)\n}\n", "")
+	if len(fixes) != 1 || fixes[0] != (language.AutoFix{Name: "add_missing_import", File: "main.go", Detail: `"fmt"`}) {
+		t.Errorf("fixes = %+v", fixes)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	result, err := New().Compile(ctx, mainCode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success {
+		t.Errorf("fixed code doesn't build:\n%s", result.Output)
+	}
+}
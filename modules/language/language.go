@@ -0,0 +1,281 @@
+package language
+
+import (
+	"context"
+	"fmt"
+	"llama/modules/extraction"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// CORE TYPES
+// ============================================================================
+
+// ErrorType classifies the nature of a compilation/execution error
+type ErrorType int
+
+const (
+	ErrorTypeUnknown        ErrorType = iota
+	ErrorTypeInfrastructure           // go.mod exists, missing dependencies, etc.
+	ErrorTypeSyntax                   // Parse error, invalid syntax
+	ErrorTypeType                     // Type mismatch, undefined symbols
+	ErrorTypeLogic                    // Test failures, logic errors
+	ErrorTypeRuntime                  // Crashes, segfaults, panics
+	ErrorTypeSuccess                  // No error
+)
+
+func (e ErrorType) String() string {
+	switch e {
+	case ErrorTypeInfrastructure:
+		return "infrastructure"
+	case ErrorTypeSyntax:
+		return "syntax"
+	case ErrorTypeType:
+		return "type"
+	case ErrorTypeLogic:
+		return "logic"
+	case ErrorTypeRuntime:
+		return "runtime"
+	case ErrorTypeSuccess:
+		return "success"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a single located compiler, vet or test message
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Phase   string `json:"phase"`          // "build", "vet", "test"
+	Test    string `json:"test,omitempty"` // Failing test, for test diagnostics
+	Source  string `json:"source,omitempty"`
+}
+
+// Location renders file:line[:col], or "" when the diagnostic has no file
+func (d Diagnostic) Location() string {
+	switch {
+	case d.File == "":
+		return ""
+	case d.Column > 0:
+		return fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	case d.Line > 0:
+		return fmt.Sprintf("%s:%d", d.File, d.Line)
+	default:
+		return d.File
+	}
+}
+
+// StageResult is the outcome of one build or test step of a backend
+type StageResult struct {
+	Name       string `json:"name"` // e.g. "build", "test"
+	Command    string `json:"command"`
+	Status     string `json:"status"` // "ok", "failed", "skipped"
+	ExitCode   int    `json:"exitCode"`
+	DurationMs int64  `json:"durationMs"`
+	Output     string `json:"output,omitempty"`
+}
+
+// TestCase is the result of one test or subtest
+type TestCase struct {
+	Name      string `json:"name"`
+	Parent    string `json:"parent,omitempty"` // Enclosing test for subtests
+	Status    string `json:"status"`           // "pass", "fail", "skip"
+	ElapsedMs int64  `json:"elapsedMs"`
+	Output    string `json:"output,omitempty"`
+}
+
+// TestReport holds per-test results. The counts cover leaf tests only.
+type TestReport struct {
+	Tests     []TestCase `json:"tests"`
+	Passed    int        `json:"passed"`
+	Failed    int        `json:"failed"`
+	Skipped   int        `json:"skipped"`
+	PassRatio float64    `json:"passRatio"` // Passed / (Passed + Failed)
+}
+
+// FailedTests returns the names of the failed leaf tests
+func (r *TestReport) FailedTests() []string {
+	parents := make(map[string]bool)
+	for _, tc := range r.Tests {
+		if tc.Parent != "" {
+			parents[tc.Parent] = true
+		}
+	}
+
+	var failed []string
+	for _, tc := range r.Tests {
+		if tc.Status == "fail" && !parents[tc.Name] {
+			failed = append(failed, tc.Name)
+		}
+	}
+	return failed
+}
+
+// CompilationResult holds the output of a single compilation attempt
+type CompilationResult struct {
+	Success       bool
+	ExitCode      int
+	CompileErrors []string     // Compilation-time errors
+	TestErrors    []string     // Test-time failures
+	Diagnostics   []Diagnostic // Structured form of the errors, when the backend provides it
+	Output        string       // Raw combined output
+	ErrorType     ErrorType
+	ErrorSubtype  string        // Finer category, e.g. "unused_import"; backend-specific, may be empty
+	Stage         string        // Build/test stage that failed, e.g. "test_compile"; may be empty
+	Stages        []StageResult // Steps the backend ran, in order; may be empty
+	Tests         *TestReport   // Per-test results, nil if the backend has none or tests didn't run
+	ExecutionTime time.Duration
+}
+
+// ============================================================================
+// LANGUAGE INTERFACE
+// ============================================================================
+
+// Language is everything the pipeline needs to generate, extract, build and
+// test code in one programming language. Backends live in their own package
+// under modules/language and register themselves from init.
+type Language interface {
+	// Name is the identifier used in requests, e.g. "go"
+	Name() string
+
+	// FormatInstructions is appended to the user's prompt to tell the LLM
+	// how to lay out the main and test code
+	FormatInstructions() string
+
+	// Extractor returns the strategies used to pull main and test code out
+	// of an LLM response
+	Extractor() *extraction.Extractor
+
+	// FileNames are the names the main and test code are written to in the
+	// workspace, as they appear in diagnostics and stack traces
+	FileNames() (mainFile, testFile string)
+
+	// Compile builds the code and runs its tests in an isolated workspace.
+	// A returned error means the backend itself failed, not the code.
+	Compile(ctx context.Context, mainCode, testCode string) (*CompilationResult, error)
+
+	// ClassifyError derives the ErrorType of a failed result
+	ClassifyError(result *CompilationResult) ErrorType
+}
+
+// Recoverer is implemented by backends that can work around infrastructure
+// failures on their own, without new code from the LLM
+type Recoverer interface {
+	// RecoveryActions lists the actions worth trying for a result that
+	// failed with ErrorTypeInfrastructure, in order; none for other results
+	RecoveryActions(result *CompilationResult) []string
+	// Recover performs action and compiles the same code again
+	Recover(ctx context.Context, action, mainCode, testCode string) (*CompilationResult, error)
+}
+
+// Phases of Compile reported to a PhaseReporter's caller
+const (
+	PhaseCompiling = "compiling"
+	PhaseTesting   = "testing"
+)
+
+// PhaseReporter is implemented by backends that can tell when Compile moves
+// from building the code on to running its tests
+type PhaseReporter interface {
+	// CompileWithPhases is Compile, calling onPhase with each phase as it
+	// starts
+	CompileWithPhases(ctx context.Context, mainCode, testCode string, onPhase func(phase string)) (*CompilationResult, error)
+}
+
+// Versioner is implemented by backends that can report the toolchain they
+// build with, e.g. for a record of how a job ran
+type Versioner interface {
+	ToolchainVersion(ctx context.Context) (string, error)
+}
+
+// AutoFix is one deterministic rewrite of generated code
+type AutoFix struct {
+	Name   string `json:"name"` // e.g. "remove_unused_import"
+	File   string `json:"file"`
+	Detail string `json:"detail"`
+}
+
+// AutoFixer is implemented by backends that can repair mechanical mistakes
+// in generated code before it is compiled
+type AutoFixer interface {
+	// AutoFix returns the rewritten code and the fixes made, in order
+	AutoFix(mainCode, testCode string) (string, string, []AutoFix)
+}
+
+// ============================================================================
+// REGISTRY
+// ============================================================================
+
+// Registry maps language names to their backends
+type Registry struct {
+	mu        sync.RWMutex
+	languages map[string]Language
+}
+
+func NewRegistry() *Registry {
+	return &Registry{languages: make(map[string]Language)}
+}
+
+// Register adds a backend. It panics if the name is already taken, since
+// that can only be a programming error.
+func (r *Registry) Register(lang Language) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := strings.ToLower(lang.Name())
+	if _, exists := r.languages[name]; exists {
+		panic("language: Register called twice for " + name)
+	}
+	r.languages[name] = lang
+}
+
+// Lookup returns the backend registered under name (case-insensitive)
+func (r *Registry) Lookup(name string) (Language, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lang, ok := r.languages[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s (supported: %s)", name, strings.Join(r.namesLocked(), ", "))
+	}
+	return lang, nil
+}
+
+// Names returns the registered language names in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.languages))
+	for name := range r.languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register adds a backend to the default registry
+func Register(lang Language) {
+	defaultRegistry.Register(lang)
+}
+
+// Lookup finds a backend in the default registry
+func Lookup(name string) (Language, error) {
+	return defaultRegistry.Lookup(name)
+}
+
+// Names lists the languages in the default registry
+func Names() []string {
+	return defaultRegistry.Names()
+}
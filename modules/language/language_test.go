@@ -0,0 +1,81 @@
+package language
+
+import (
+	"context"
+	"llama/modules/extraction"
+	"strings"
+	"testing"
+)
+
+type fakeLanguage struct{ name string }
+
+func (f fakeLanguage) Name() string                     { return f.name }
+func (f fakeLanguage) FormatInstructions() string       { return "" }
+func (f fakeLanguage) Extractor() *extraction.Extractor { return extraction.NewExtractor() }
+func (f fakeLanguage) FileNames() (string, string)      { return "main.x", "test.x" }
+func (f fakeLanguage) Compile(ctx context.Context, mainCode, testCode string) (*CompilationResult, error) {
+	return &CompilationResult{Success: true, ErrorType: ErrorTypeSuccess}, nil
+}
+func (f fakeLanguage) ClassifyError(result *CompilationResult) ErrorType { return result.ErrorType }
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(fakeLanguage{name: "zig"})
+	registry.Register(fakeLanguage{name: "ada"})
+
+	lang, err := registry.Lookup("ZIG")
+	if err != nil || lang.Name() != "zig" {
+		t.Errorf("Lookup(ZIG) = %v, %v", lang, err)
+	}
+
+	_, err = registry.Lookup("cobol")
+	if err == nil || !strings.Contains(err.Error(), "supported: ada, zig") {
+		t.Errorf("Lookup(cobol) error = %v, want list of supported languages", err)
+	}
+
+	if names := registry.Names(); strings.Join(names, ",") != "ada,zig" {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(fakeLanguage{name: "zig"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	registry.Register(fakeLanguage{name: "zig"})
+}
+
+func TestFailedTestsSkipsParents(t *testing.T) {
+	report := &TestReport{Tests: []TestCase{
+		{Name: "TestA", Status: "fail"},
+		{Name: "TestB", Status: "fail"},
+		{Name: "TestB/x", Parent: "TestB", Status: "pass"},
+		{Name: "TestB/y", Parent: "TestB", Status: "fail"},
+		{Name: "TestC", Status: "pass"},
+	}}
+	if got := strings.Join(report.FailedTests(), ","); got != "TestA,TestB/y" {
+		t.Errorf("FailedTests = %s", got)
+	}
+}
+
+func TestDiagnosticLocation(t *testing.T) {
+	tests := []struct {
+		d    Diagnostic
+		want string
+	}{
+		{Diagnostic{File: "main.go", Line: 3, Column: 7}, "main.go:3:7"},
+		{Diagnostic{File: "main_test.go", Line: 9}, "main_test.go:9"},
+		{Diagnostic{File: "main.go"}, "main.go"},
+		{Diagnostic{Message: "test failed"}, ""},
+	}
+	for _, test := range tests {
+		if got := test.d.Location(); got != test.want {
+			t.Errorf("Location() = %q, want %q", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package python
+
+import (
+	"context"
+	"llama/modules/compiler_v2/python_compiler_v2"
+	"llama/modules/extraction"
+	"llama/modules/language"
+)
+
+// ============================================================================
+// PYTHON LANGUAGE BACKEND
+// ============================================================================
+
+const formatInstructions = `
+IMPORTANT: Generate Python code in this exact format:
+- Two code blocks separated by a blank line
+- First block: main.py with functions, main() guarded by if __name__ == "__main__"
+- Second block: test_main.py that imports from main (from main import ...) and tests it with unittest
+- Provide code only, no explanations
+`
+
+func init() {
+	language.Register(New())
+}
+
+// Python byte-compiles and tests code with PythonCompilerV2
+type Python struct{}
+
+func New() *Python {
+	return &Python{}
+}
+
+func (p *Python) Name() string {
+	return "python"
+}
+
+func (p *Python) FormatInstructions() string {
+	return formatInstructions
+}
+
+func (p *Python) Extractor() *extraction.Extractor {
+	return extraction.NewExtractorWithStrategies(
+		extraction.StandardFormatStrategy{},
+		extraction.LanguageTagStrategy{Tags: []string{"python", "py"}},
+		extraction.SingleFileStrategy{},
+	)
+}
+
+func (p *Python) FileNames() (string, string) {
+	return "main.py", "test_main.py"
+}
+
+func (p *Python) Compile(ctx context.Context, mainCode, testCode string) (*language.CompilationResult, error) {
+	pyResult, err := python_compiler_v2.NewPythonCompilerV2().Compile(ctx, mainCode, testCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &language.CompilationResult{
+		Success:       pyResult.Success,
+		ExitCode:      pyResult.ExitCode,
+		CompileErrors: pyResult.CompileErrors,
+		TestErrors:    pyResult.TestErrors,
+		Output:        pyResult.RawOutput,
+		ErrorType:     mapErrorType(pyResult.ErrorType),
+		ExecutionTime: pyResult.ExecutionTime,
+	}, nil
+}
+
+// ToolchainVersion implements language.Versioner
+func (p *Python) ToolchainVersion(ctx context.Context) (string, error) {
+	return python_compiler_v2.Version(ctx)
+}
+
+func (p *Python) ClassifyError(result *language.CompilationResult) language.ErrorType {
+	if result.Success {
+		return language.ErrorTypeSuccess
+	}
+	inTests := len(result.TestErrors) > 0
+	return mapErrorType(python_compiler_v2.ClassifyPythonError(result.Output, inTests))
+}
+
+// mapErrorType translates the Python compiler's error classes to language.ErrorType
+func mapErrorType(e python_compiler_v2.ErrorTypePython) language.ErrorType {
+	switch e {
+	case python_compiler_v2.ErrorTypePythonInfrastructure:
+		return language.ErrorTypeInfrastructure
+	case python_compiler_v2.ErrorTypePythonSyntax:
+		return language.ErrorTypeSyntax
+	case python_compiler_v2.ErrorTypePythonType:
+		return language.ErrorTypeType
+	case python_compiler_v2.ErrorTypePythonLogic:
+		return language.ErrorTypeLogic
+	case python_compiler_v2.ErrorTypePythonRuntime:
+		return language.ErrorTypeRuntime
+	case python_compiler_v2.ErrorTypePythonSuccess:
+		return language.ErrorTypeSuccess
+	default:
+		return language.ErrorTypeUnknown
+	}
+}
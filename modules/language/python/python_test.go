@@ -0,0 +1,38 @@
+package python
+
+import (
+	"context"
+	"llama/modules/language"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	mainCode := "def add(a, b):\n    return a + b\n"
+	testCode := "import unittest\nfrom main import add\n\n\nclass TestAdd(unittest.TestCase):\n    def test_add(self):\n        self.assertEqual(add(2, 3), 6)\n"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := New().Compile(ctx, mainCode, testCode)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if result.ErrorType == language.ErrorTypeInfrastructure {
+		t.Skipf("python unavailable: %s", result.Output)
+	}
+	if result.Success || result.ErrorType != language.ErrorTypeLogic {
+		t.Errorf("expected logic error, got success=%v errorType=%s output:\n%s", result.Success, result.ErrorType, result.Output)
+	}
+	if got := New().ClassifyError(result); got != result.ErrorType {
+		t.Errorf("ClassifyError = %s, want %s", got, result.ErrorType)
+	}
+}
+
+func TestExtractor(t *testing.T) {
+	response := "```python\ndef add(a, b):\n    return a + b\n```\n\n```python\nfrom main import add\n```"
+	mainCode, testCode, _, err := New().Extractor().Extract(response)
+	if err != nil || mainCode == "" || testCode != "from main import add" {
+		t.Errorf("Extract = %q, %q, %v", mainCode, testCode, err)
+	}
+}
@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"time"
+)
+
+// ============================================================================
+// CORE TYPES
+// ============================================================================
+
+// Messages and options are Ollama's; other providers translate them
+type (
+	Message = ollamaimplementation.Message
+	Options = ollamaimplementation.Options
+)
+
+const (
+	RoleSystem    = ollamaimplementation.RoleSystem
+	RoleUser      = ollamaimplementation.RoleUser
+	RoleAssistant = ollamaimplementation.RoleAssistant
+)
+
+// Errors every provider reports its failures as, for errors.Is
+var (
+	ErrModelNotFound     = ollamaimplementation.ErrModelNotFound
+	ErrServerUnavailable = ollamaimplementation.ErrServerUnavailable
+	ErrOutOfMemory       = ollamaimplementation.ErrOutOfMemory
+	ErrTimeout           = ollamaimplementation.ErrTimeout
+)
+
+// Request is one turn of a conversation. Messages is the whole conversation
+// so far, oldest first, ending with the new user message.
+type Request struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Options  *Options  `json:"options,omitempty"`
+}
+
+// LLMClient generates the next message of a conversation. Implementations
+// must be safe for concurrent use and stop when ctx is done.
+type LLMClient interface {
+	Complete(ctx context.Context, req Request) (Message, error)
+}
+
+// Streamer is implemented by clients that can pass the reply on while it
+// is being generated
+type Streamer interface {
+	// Stream is Complete, calling onToken with every piece of the reply as
+	// it arrives
+	Stream(ctx context.Context, req Request, onToken func(string)) (Message, error)
+}
+
+// Stream runs req on client, passing the reply to onToken as it is
+// generated if client is a Streamer and all at once otherwise
+func Stream(ctx context.Context, client LLMClient, req Request, onToken func(string)) (Message, error) {
+	if streamer, ok := client.(Streamer); ok {
+		return streamer.Stream(ctx, req, onToken)
+	}
+	msg, err := client.Complete(ctx, req)
+	if err == nil && msg.Content != "" {
+		onToken(msg.Content)
+	}
+	return msg, err
+}
+
+// ============================================================================
+// PROVIDERS
+// ============================================================================
+
+// Provider types
+const (
+	ProviderOllama   = "ollama"
+	ProviderOpenAI   = "openai"
+	ProviderScripted = "scripted"
+)
+
+// Config describes a provider, as read from the configuration file
+type Config struct {
+	Type      string   `json:"type"`                // One of the Provider constants
+	Endpoint  string   `json:"endpoint,omitempty"`  // Server base URL; Ollama's defaults to the local server
+	APIKey    string   `json:"apiKey,omitempty"`    // Sent as a bearer token by the OpenAI client
+	Responses []string `json:"responses,omitempty"` // Replies of the scripted provider, in order
+}
+
+// Validate checks that New can build the provider
+func (c Config) Validate() error {
+	switch c.Type {
+	case ProviderOllama, ProviderScripted:
+		return nil
+	case ProviderOpenAI:
+		if c.Endpoint == "" {
+			return fmt.Errorf("an openai provider needs an endpoint")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown provider type %q", c.Type)
+	}
+}
+
+// New builds the provider c describes. timeout limits each attempt at a
+// request; failures that may be transient are retried.
+func New(c Config, timeout time.Duration) (LLMClient, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch c.Type {
+	case ProviderOpenAI:
+		client := NewOpenAI(c.Endpoint, c.APIKey)
+		client.Timeout = timeout
+		return client, nil
+	case ProviderScripted:
+		return NewScripted(c.Responses...), nil
+	default:
+		client := ollamaimplementation.NewClient()
+		client.Timeout = timeout
+		if c.Endpoint != "" {
+			client.Endpoint = c.Endpoint + "/api/generate"
+			client.ChatEndpoint = c.Endpoint + "/api/chat"
+			client.TagsEndpoint = c.Endpoint + "/api/tags"
+			client.PullEndpoint = c.Endpoint + "/api/pull"
+		}
+		return &Ollama{Client: client}, nil
+	}
+}
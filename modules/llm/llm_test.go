@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOpenAIComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("request to %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "qwen" || len(req.Messages) != 2 || *req.Temperature != 0.3 || req.MaxTokens != nil || req.Stream {
+			t.Errorf("request = %+v", req)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"fn main() {}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	temperature, unlimited := 0.3, -1
+	client := NewOpenAI(server.URL+"/", "secret")
+	msg, err := client.Complete(context.Background(), Request{
+		Model:    "qwen",
+		Messages: []Message{{Role: RoleSystem, Content: "Be brief."}, {Role: RoleUser, Content: "hi"}},
+		Options:  &Options{Temperature: &temperature, NumPredict: &unlimited},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != (Message{Role: RoleAssistant, Content: "fn main() {}"}) {
+		t.Errorf("msg = %+v", msg)
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		want     error
+		requests int32
+	}{
+		{"model missing", http.StatusNotFound, `{"error":{"message":"The model 'nope' does not exist"}}`, ErrModelNotFound, 1},
+		{"out of memory", http.StatusInternalServerError, `{"error":{"message":"CUDA out of memory"}}`, ErrOutOfMemory, 1},
+		{"loading", http.StatusServiceUnavailable, `{"error":{"message":"Loading model"}}`, ErrServerUnavailable, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := &OpenAI{Endpoint: server.URL + "/v1", MaxRetries: 2, Backoff: time.Millisecond}
+			if _, err := client.Complete(context.Background(), Request{Model: "nope"}); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if requests != tt.requests {
+				t.Errorf("%d requests, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestScripted(t *testing.T) {
+	s := NewScripted("first", "second")
+	for _, want := range []string{"first", "second"} {
+		msg, err := s.Complete(context.Background(), Request{Model: "any"})
+		if err != nil || msg.Content != want || msg.Role != RoleAssistant {
+			t.Errorf("got %+v, %v; want %q", msg, err, want)
+		}
+	}
+	if _, err := s.Complete(context.Background(), Request{}); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("err = %v, want ErrScriptExhausted", err)
+	}
+	if n := len(s.Requests()); n != 3 {
+		t.Errorf("recorded %d requests, want 3", n)
+	}
+}
+
+// completer is an LLMClient that can't stream
+type completer func(context.Context, Request) (Message, error)
+
+func (f completer) Complete(ctx context.Context, req Request) (Message, error) { return f(ctx, req) }
+
+func TestStream(t *testing.T) {
+	var tokens []string
+	onToken := func(text string) { tokens = append(tokens, text) }
+
+	msg, err := Stream(context.Background(), NewScripted("package main\n\nfunc main() {}\n"), Request{}, onToken)
+	if err != nil || len(tokens) != 3 || strings.Join(tokens, "") != msg.Content {
+		t.Errorf("scripted: tokens %q, err %v", tokens, err)
+	}
+
+	tokens = nil
+	whole := completer(func(context.Context, Request) (Message, error) {
+		return Message{Role: RoleAssistant, Content: "all at once"}, nil
+	})
+	if _, err := Stream(context.Background(), whole, Request{}, onToken); err != nil || len(tokens) != 1 || tokens[0] != "all at once" {
+		t.Errorf("fallback: tokens %q, err %v", tokens, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, c := range []Config{{Type: ProviderOllama}, {Type: ProviderOpenAI, Endpoint: "http://localhost:8080"}, {Type: ProviderScripted}} {
+		if _, err := New(c, time.Minute); err != nil {
+			t.Errorf("New(%+v): %v", c, err)
+		}
+	}
+	for _, c := range []Config{{Type: "anthropic"}, {Type: ProviderOpenAI}} {
+		if _, err := New(c, time.Minute); err == nil {
+			t.Errorf("New(%+v) succeeded", c)
+		}
+	}
+
+	client, _ := New(Config{Type: ProviderOllama, Endpoint: "http://gpu-box:11434"}, time.Minute)
+	if ollama := client.(*Ollama); ollama.Client.ChatEndpoint != "http://gpu-box:11434/api/chat" || ollama.Client.Timeout != time.Minute {
+		t.Errorf("client = %+v", ollama.Client)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	timeout := completer(func(context.Context, Request) (Message, error) {
+		return Message{}, &failure{ErrTimeout}
+	})
+	first, second := Request{Model: "m", Messages: []Message{{Role: RoleUser, Content: "one"}}}, Request{Model: "m", Messages: []Message{{Role: RoleUser, Content: "two"}}}
+
+	recorder := NewRecorder(NewScripted("reply one", "reply two"))
+	recorder.Complete(context.Background(), first)
+	var tokens []string
+	recorder.Stream(context.Background(), second, func(text string) { tokens = append(tokens, text) })
+	failing := NewRecorder(timeout)
+	failing.Complete(context.Background(), first)
+
+	exchanges := append(recorder.Exchanges(), failing.Exchanges()...)
+	if len(exchanges) != 3 || exchanges[1].Response != "reply two" || len(tokens) != 1 || exchanges[2].ErrorKind != "timeout" {
+		t.Fatalf("exchanges = %+v, tokens %q", exchanges, tokens)
+	}
+	if _, ok := Base(recorder).(*Scripted); !ok {
+		t.Errorf("Base(recorder) = %T", Base(recorder))
+	}
+
+	// Identical requests get their own responses whatever the order; a
+	// request never made gets the earliest one left
+	replay := NewReplay(exchanges)
+	if msg, err := replay.Complete(context.Background(), second); err != nil || msg.Content != "reply two" {
+		t.Errorf("second: %+v, %v", msg, err)
+	}
+	if msg, err := replay.Complete(context.Background(), Request{Model: "other"}); err != nil || msg.Content != "reply one" {
+		t.Errorf("new request: %+v, %v", msg, err)
+	}
+	if _, err := replay.Complete(context.Background(), first); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want ErrTimeout", err)
+	}
+	if _, err := replay.Complete(context.Background(), first); !errors.Is(err, ErrReplayExhausted) {
+		t.Errorf("err = %v, want ErrReplayExhausted", err)
+	}
+	if replay.Diverged() != 1 {
+		t.Errorf("diverged %d, want 1", replay.Diverged())
+	}
+}
+
+// failure is an error wrapping one of the Err variables
+type failure struct{ err error }
+
+func (f *failure) Error() string { return "failed: " + f.err.Error() }
+func (f *failure) Unwrap() error { return f.err }
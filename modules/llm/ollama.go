@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	ollamaimplementation "llama/modules/ollama-implementation"
+)
+
+// Ollama is the provider for an Ollama server's chat endpoint
+type Ollama struct {
+	Client *ollamaimplementation.Client
+}
+
+func (o *Ollama) Complete(ctx context.Context, req Request) (Message, error) {
+	return o.chat(ctx, req, nil)
+}
+
+// Stream implements Streamer
+func (o *Ollama) Stream(ctx context.Context, req Request, onToken func(string)) (Message, error) {
+	return o.chat(ctx, req, func(chunk ollamaimplementation.ChatResponse) {
+		if chunk.Message.Content != "" {
+			onToken(chunk.Message.Content)
+		}
+	})
+}
+
+func (o *Ollama) chat(ctx context.Context, req Request, onChunk func(ollamaimplementation.ChatResponse)) (Message, error) {
+	result, err := o.Client.Chat(ctx, ollamaimplementation.ChatRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Options:  req.Options,
+	}, onChunk)
+	if err != nil {
+		return Message{}, err
+	}
+	return result.Message, nil
+}
@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// OPENAI-COMPATIBLE PROVIDER
+// ============================================================================
+
+// OpenAI is the provider for servers implementing OpenAI's chat completions
+// API, such as llama.cpp's server and vLLM
+type OpenAI struct {
+	Endpoint   string       // Base URL, e.g. http://localhost:8080 or http://localhost:8000/v1
+	APIKey     string       // Sent as a bearer token if set
+	HTTPClient *http.Client // Defaults to http.DefaultClient
+
+	Timeout    time.Duration // Per attempt; 0 means only ctx limits it
+	MaxRetries int           // Retries of transient failures
+	Backoff    time.Duration // Wait before the first retry, doubled after every retry
+}
+
+// NewOpenAI returns a client with the same retry policy as Ollama's
+func NewOpenAI(endpoint, apiKey string) *OpenAI {
+	return &OpenAI{
+		Endpoint:   endpoint,
+		APIKey:     apiKey,
+		MaxRetries: ollamaimplementation.DefaultMaxRetries,
+		Backoff:    ollamaimplementation.DefaultBackoff,
+	}
+}
+
+type openAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	MaxTokens   *int      `json:"max_tokens,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+	Stream      bool      `json:"stream"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// openAIRequestFor translates req. num_ctx is the server's to decide and
+// has no equivalent; a negative num_predict means no limit.
+func openAIRequestFor(req Request) openAIRequest {
+	body := openAIRequest{Model: req.Model, Messages: req.Messages}
+	if o := req.Options; o != nil {
+		body.Temperature, body.Seed, body.Stop = o.Temperature, o.Seed, o.Stop
+		if o.NumPredict != nil && *o.NumPredict > 0 {
+			body.MaxTokens = o.NumPredict
+		}
+	}
+	return body
+}
+
+// url is the chat completions URL under Endpoint
+func (c *OpenAI) url() string {
+	base := strings.TrimSuffix(c.Endpoint, "/")
+	if !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	return base + "/chat/completions"
+}
+
+func (c *OpenAI) Complete(ctx context.Context, req Request) (Message, error) {
+	body, err := json.Marshal(openAIRequestFor(req))
+	if err != nil {
+		return Message{}, err
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		msg, transient, err := c.completeOnce(ctx, body)
+		if err == nil || !transient || attempt >= c.MaxRetries || ctx.Err() != nil {
+			return msg, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// completeOnce makes one attempt at a request and reports whether a failure
+// may go away if it is repeated
+func (c *OpenAI) completeOnce(ctx context.Context, body []byte) (msg Message, transient bool, err error) {
+	attemptCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if attemptCtx.Err() != nil {
+			err, transient = attemptCtx.Err(), false
+		}
+		if ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("%w after %v", ErrTimeout, c.Timeout)
+		}
+	}()
+
+	httpReq, err := http.NewRequestWithContext(attemptCtx, http.MethodPost, c.url(), bytes.NewReader(body))
+	if err != nil {
+		return Message{}, false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return Message{}, true, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := openAIError(resp)
+		transient := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return Message{}, transient && !errors.Is(err, ErrModelNotFound) && !errors.Is(err, ErrOutOfMemory), err
+	}
+
+	var completion openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return Message{}, false, fmt.Errorf("reading response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return Message{}, false, fmt.Errorf("response has no choices")
+	}
+	msg = completion.Choices[0].Message
+	msg.Role = RoleAssistant
+	return msg, false, nil
+}
+
+// openAIError classifies an error response, which comes as
+// {"error": {"message": ...}}, by status and message
+func openAIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &payload) == nil && payload.Error.Message != "" {
+		message = payload.Error.Message
+	}
+	if message == "" {
+		message = resp.Status
+	}
+
+	lower := strings.ToLower(message)
+	switch {
+	case resp.StatusCode == http.StatusNotFound || strings.Contains(lower, "does not exist") || strings.Contains(lower, "not found"):
+		return fmt.Errorf("%w: %s (HTTP %d)", ErrModelNotFound, message, resp.StatusCode)
+	case strings.Contains(lower, "out of memory"):
+		return fmt.Errorf("%w: %s (HTTP %d)", ErrOutOfMemory, message, resp.StatusCode)
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return fmt.Errorf("%w: %s (HTTP %d)", ErrServerUnavailable, message, resp.StatusCode)
+	default:
+		return fmt.Errorf("%s (HTTP %d)", message, resp.StatusCode)
+	}
+}
@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// RECORD AND REPLAY
+// ============================================================================
+
+// Exchange is one request to an LLM and what came back
+type Exchange struct {
+	Request    Request `json:"request"`
+	Response   string  `json:"response"`
+	Error      string  `json:"error,omitempty"`
+	ErrorKind  string  `json:"errorKind,omitempty"` // Which of the Err variables Error matched, so replays fail the same way
+	DurationMs int64   `json:"durationMs"`
+}
+
+// errorKinds name the errors an exchange can be replayed with
+var errorKinds = map[string]error{
+	"model_not_found":    ErrModelNotFound,
+	"server_unavailable": ErrServerUnavailable,
+	"out_of_memory":      ErrOutOfMemory,
+	"timeout":            ErrTimeout,
+}
+
+// Recorder is a client that keeps every exchange of the client it wraps
+type Recorder struct {
+	client LLMClient
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+func NewRecorder(client LLMClient) *Recorder {
+	return &Recorder{client: client}
+}
+
+// Unwrap returns the recorded client
+func (r *Recorder) Unwrap() LLMClient { return r.client }
+
+func (r *Recorder) Complete(ctx context.Context, req Request) (Message, error) {
+	return r.record(req, func() (Message, error) { return r.client.Complete(ctx, req) })
+}
+
+// Stream implements Streamer, streaming if the recorded client does
+func (r *Recorder) Stream(ctx context.Context, req Request, onToken func(string)) (Message, error) {
+	return r.record(req, func() (Message, error) { return Stream(ctx, r.client, req, onToken) })
+}
+
+func (r *Recorder) record(req Request, do func() (Message, error)) (Message, error) {
+	start := time.Now()
+	msg, err := do()
+
+	exchange := Exchange{Request: req, Response: msg.Content, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		exchange.Error = err.Error()
+		for kind, target := range errorKinds {
+			if errors.Is(err, target) {
+				exchange.ErrorKind = kind
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, exchange)
+	r.mu.Unlock()
+	return msg, err
+}
+
+// Exchanges returns the exchanges so far, in the order they finished
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.exchanges...)
+}
+
+// ErrReplayExhausted is returned once a replay has used up its recording
+var ErrReplayExhausted = errors.New("no recorded exchanges left to replay")
+
+// Replay is a client that answers with recorded exchanges. A request gets
+// the response recorded for an identical request; a request that was never
+// made, because something upstream of the LLM changed since the recording,
+// gets the earliest response not yet used. Diverged counts those.
+type Replay struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+	diverged  int
+}
+
+func NewReplay(exchanges []Exchange) *Replay {
+	return &Replay{exchanges: exchanges, used: make([]bool, len(exchanges))}
+}
+
+func (r *Replay) Complete(ctx context.Context, req Request) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+	key, err := json.Marshal(req)
+	if err != nil {
+		return Message{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	match, next := -1, -1
+	for i, exchange := range r.exchanges {
+		if r.used[i] {
+			continue
+		}
+		if next < 0 {
+			next = i
+		}
+		if recorded, _ := json.Marshal(exchange.Request); string(recorded) == string(key) {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		if next < 0 {
+			return Message{}, ErrReplayExhausted
+		}
+		match = next
+		r.diverged++
+	}
+	r.used[match] = true
+
+	exchange := r.exchanges[match]
+	if exchange.Error != "" {
+		if kind, ok := errorKinds[exchange.ErrorKind]; ok {
+			return Message{}, &replayedError{message: exchange.Error, kind: kind}
+		}
+		return Message{}, errors.New(exchange.Error)
+	}
+	return Message{Role: RoleAssistant, Content: exchange.Response}, nil
+}
+
+// Diverged returns how many requests had no identical recorded request
+func (r *Replay) Diverged() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.diverged
+}
+
+// replayedError is a recorded error that still matches its Err variable
+type replayedError struct {
+	message string
+	kind    error
+}
+
+func (e *replayedError) Error() string { return e.message }
+func (e *replayedError) Unwrap() error { return e.kind }
+
+// Base returns the client at the bottom of any Recorders
+func Base(client LLMClient) LLMClient {
+	for {
+		recorder, ok := client.(*Recorder)
+		if !ok {
+			return client
+		}
+		client = recorder.Unwrap()
+	}
+}
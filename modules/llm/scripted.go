@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrScriptExhausted is returned once a scripted provider has given all of
+// its responses
+var ErrScriptExhausted = errors.New("scripted provider has no responses left")
+
+// Scripted is a provider that replies with canned responses in order,
+// whatever it's asked. It records the requests it gets.
+type Scripted struct {
+	mu        sync.Mutex
+	responses []string
+	requests  []Request
+}
+
+func NewScripted(responses ...string) *Scripted {
+	return &Scripted{responses: responses}
+}
+
+func (s *Scripted) Complete(ctx context.Context, req Request) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	if len(s.requests) > len(s.responses) {
+		return Message{}, ErrScriptExhausted
+	}
+	return Message{Role: RoleAssistant, Content: s.responses[len(s.requests)-1]}, nil
+}
+
+// Stream implements Streamer, passing the response on a line at a time
+func (s *Scripted) Stream(ctx context.Context, req Request, onToken func(string)) (Message, error) {
+	msg, err := s.Complete(ctx, req)
+	if err != nil {
+		return msg, err
+	}
+	for _, line := range strings.SplitAfter(msg.Content, "\n") {
+		if line != "" {
+			onToken(line)
+		}
+	}
+	return msg, nil
+}
+
+// Requests returns the requests received so far, oldest first
+func (s *Scripted) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
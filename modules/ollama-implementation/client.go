@@ -0,0 +1,347 @@
+package ollamaimplementation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// CLIENT
+// ============================================================================
+
+const (
+	DefaultMaxRetries = 2
+	DefaultBackoff    = 500 * time.Millisecond // Doubled after every retry
+)
+
+// Errors a request can fail with, for errors.Is. Server-side failures come
+// as an *Error that unwraps to one of them.
+var (
+	ErrModelNotFound     = errors.New("model not found")
+	ErrServerUnavailable = errors.New("ollama server unavailable")
+	ErrOutOfMemory       = errors.New("not enough memory to run the model")
+	ErrTimeout           = errors.New("ollama request timed out")
+)
+
+// Error is a failure reported by the Ollama server, either as a non-200
+// status or as an {"error": ...} body
+type Error struct {
+	StatusCode int    // 0 if the error came in the middle of the stream
+	Message    string // As the server put it
+	Kind       error  // One of the Err variables, or nil if unclassified
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return "ollama: " + e.Message
+	}
+	return fmt.Sprintf("ollama: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// transient reports whether the same request may succeed if tried again
+func (e *Error) transient() bool {
+	if e.Kind == ErrModelNotFound || e.Kind == ErrOutOfMemory {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newError classifies a server error by status and message
+func newError(status int, message string) *Error {
+	e := &Error{StatusCode: status, Message: message}
+	lower := strings.ToLower(message)
+	switch {
+	case status == http.StatusNotFound || strings.Contains(lower, "not found"):
+		e.Kind = ErrModelNotFound
+	case strings.Contains(lower, "memory"):
+		e.Kind = ErrOutOfMemory
+	case status == http.StatusServiceUnavailable:
+		e.Kind = ErrServerUnavailable
+	}
+	return e
+}
+
+// Result is a completed generation
+type Result struct {
+	Response string
+	Context  []int          // Conversation to pass to the next request
+	Final    OllamaResponse // The last chunk, with timings and token counts
+}
+
+// ChatResult is a completed chat turn
+type ChatResult struct {
+	Message Message      // The assistant's reply
+	Final   ChatResponse // The last chunk, with timings and token counts
+}
+
+// Client sends requests to Ollama. The zero value is usable: it sends to
+// OllamaEndpoint and OllamaChatEndpoint without a timeout and doesn't retry.
+type Client struct {
+	Endpoint     string       // Generate endpoint, defaults to OllamaEndpoint at the time of the request
+	ChatEndpoint string       // Defaults to OllamaChatEndpoint at the time of the request
+	TagsEndpoint string       // Defaults to OllamaTagsEndpoint at the time of the request
+	PullEndpoint string       // Defaults to OllamaPullEndpoint at the time of the request
+	HTTPClient   *http.Client // Defaults to http.DefaultClient
+
+	Timeout    time.Duration // Per attempt; 0 means only ctx limits it
+	MaxRetries int           // Retries of transient failures
+	Backoff    time.Duration // Wait before the first retry
+}
+
+// NewClient returns a client with the default retry policy
+func NewClient() *Client {
+	return &Client{MaxRetries: DefaultMaxRetries, Backoff: DefaultBackoff}
+}
+
+// Generate runs req to completion on /api/generate, calling onChunk, if not
+// nil, with every streamed chunk as it arrives
+func (c *Client) Generate(ctx context.Context, req OllamaRequest, onChunk func(OllamaResponse)) (*Result, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = OllamaEndpoint
+	}
+
+	var result *Result
+	err := c.stream(ctx, endpoint, req, func(decoder *json.Decoder) (bool, error) {
+		var text strings.Builder
+		streamed := false
+		for {
+			var chunk OllamaResponse
+			if err := decodeChunk(decoder, &chunk); err != nil {
+				return streamed, err
+			}
+			text.WriteString(chunk.Response)
+			if onChunk != nil {
+				onChunk(chunk)
+				streamed = true
+			}
+			if chunk.Done {
+				result = &Result{Response: text.String(), Context: chunk.Context, Final: chunk}
+				return streamed, nil
+			}
+		}
+	})
+	return result, err
+}
+
+// Chat runs one turn of a conversation on /api/chat, calling onChunk, if
+// not nil, with every streamed chunk as it arrives
+func (c *Client) Chat(ctx context.Context, req ChatRequest, onChunk func(ChatResponse)) (*ChatResult, error) {
+	endpoint := c.ChatEndpoint
+	if endpoint == "" {
+		endpoint = OllamaChatEndpoint
+	}
+
+	var result *ChatResult
+	err := c.stream(ctx, endpoint, req, func(decoder *json.Decoder) (bool, error) {
+		var text strings.Builder
+		streamed := false
+		for {
+			var chunk ChatResponse
+			if err := decodeChunk(decoder, &chunk); err != nil {
+				return streamed, err
+			}
+			text.WriteString(chunk.Message.Content)
+			if onChunk != nil {
+				onChunk(chunk)
+				streamed = true
+			}
+			if chunk.Done {
+				result = &ChatResult{Message: Message{Role: RoleAssistant, Content: text.String()}, Final: chunk}
+				return streamed, nil
+			}
+		}
+	})
+	return result, err
+}
+
+// ListModels returns the models installed on the server
+func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	endpoint := c.TagsEndpoint
+	if endpoint == "" {
+		endpoint = OllamaTagsEndpoint
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(resp.StatusCode, errorMessage(resp.Body, resp.Status))
+	}
+	var tags struct {
+		Models []ModelInfo `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("reading model list: %w", err)
+	}
+	return tags.Models, nil
+}
+
+// Pull downloads a model, calling onProgress, if not nil, with every status
+// line the server sends. Downloads take as long as they take: Timeout does
+// not apply, only ctx stops them.
+func (c *Client) Pull(ctx context.Context, model string, onProgress func(PullProgress)) error {
+	endpoint := c.PullEndpoint
+	if endpoint == "" {
+		endpoint = OllamaPullEndpoint
+	}
+
+	untimed := *c
+	untimed.Timeout = 0
+	return untimed.stream(ctx, endpoint, PullRequest{Model: model, Stream: true}, func(decoder *json.Decoder) (bool, error) {
+		streamed := false
+		for {
+			var progress PullProgress
+			if err := decodeChunk(decoder, &progress); err != nil {
+				return streamed, err
+			}
+			if onProgress != nil {
+				onProgress(progress)
+				streamed = true
+			}
+			if progress.Status == "success" {
+				return streamed, nil
+			}
+		}
+	})
+}
+
+// stream posts req to endpoint and hands the response to read, which
+// reports whether it passed any chunk on. Failures before that which may be
+// transient (the server being unreachable or answering 429 or 5xx) are
+// retried with exponential backoff; once a chunk has been passed on the
+// request is not retried. Cancelling ctx stops the generation.
+func (c *Client) stream(ctx context.Context, endpoint string, req interface{}, read func(*json.Decoder) (bool, error)) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		streamed, err := c.streamOnce(ctx, endpoint, body, read)
+		if err == nil || streamed || attempt >= c.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// streamOnce makes one attempt at a request
+func (c *Client) streamOnce(ctx context.Context, endpoint string, body []byte, read func(*json.Decoder) (bool, error)) (streamed bool, err error) {
+	attemptCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Report the context's error rather than what it caused, and tell a
+		// timeout of this attempt apart from the caller giving up
+		if attemptCtx.Err() != nil {
+			err = attemptCtx.Err()
+		}
+		if ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("%w after %v", ErrTimeout, c.Timeout)
+		}
+	}()
+
+	httpReq, err := http.NewRequestWithContext(attemptCtx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient().Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, newError(resp.StatusCode, errorMessage(resp.Body, resp.Status))
+	}
+	return read(json.NewDecoder(resp.Body))
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// decodeChunk reads the next chunk of a stream into v. A chunk carrying
+// {"error": ...} is returned as an *Error.
+func decodeChunk(decoder *json.Decoder, v interface{}) error {
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("reading response stream: %w", err)
+	}
+
+	var failure struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+		return newError(0, failure.Error)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// errorMessage reads the message of an error response, which Ollama sends
+// as {"error": ...}, falling back to the status line
+func errorMessage(body io.Reader, status string) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 4096))
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &payload) == nil && payload.Error != "" {
+		return payload.Error
+	}
+	if text := strings.TrimSpace(string(data)); text != "" {
+		return text
+	}
+	return status
+}
+
+// retryable reports whether err may go away if the request is repeated
+func retryable(err error) bool {
+	var serverErr *Error
+	if errors.As(err, &serverErr) {
+		return serverErr.transient()
+	}
+	return errors.Is(err, ErrServerUnavailable) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,223 @@
+package ollamaimplementation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient returns a client for server that retries without waiting long
+func testClient(server *httptest.Server) *Client {
+	return &Client{Endpoint: server.URL, MaxRetries: 2, Backoff: time.Millisecond}
+}
+
+func TestClientStreamsChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoder := json.NewEncoder(w)
+		for _, text := range []string{"Hello", ", ", "world"} {
+			encoder.Encode(OllamaResponse{Response: text})
+			w.(http.Flusher).Flush()
+		}
+		encoder.Encode(OllamaResponse{Done: true, Context: []int{4, 5}, EvalCount: 3})
+	}))
+	defer server.Close()
+
+	var chunks []string
+	result, err := testClient(server).Generate(context.Background(), OllamaRequest{Prompt: "hi", Model: model}, func(chunk OllamaResponse) {
+		chunks = append(chunks, chunk.Response)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Response != "Hello, world" || len(result.Context) != 2 || result.Final.EvalCount != 3 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(chunks) != 4 || chunks[0] != "Hello" {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		want     error
+		requests int32
+	}{
+		{"model missing", http.StatusNotFound, `{"error":"model 'nope' not found, try pulling it first"}`, ErrModelNotFound, 1},
+		{"out of memory", http.StatusInternalServerError, `{"error":"model requires more system memory (5.6 GiB) than is available (2.1 GiB)"}`, ErrOutOfMemory, 1},
+		{"overloaded", http.StatusServiceUnavailable, `{"error":"server busy"}`, ErrServerUnavailable, 3},
+		{"error in stream", http.StatusOK, `{"response":"par"}` + "\n" + `{"error":"llama runner process has terminated"}`, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := testClient(server).Generate(context.Background(), OllamaRequest{Model: "nope"}, func(OllamaResponse) {})
+
+			var serverErr *Error
+			if !errors.As(err, &serverErr) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if serverErr.Kind != tt.want {
+				t.Errorf("Kind = %v, want %v", serverErr.Kind, tt.want)
+			}
+			if requests != tt.requests {
+				t.Errorf("%d requests, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(mockResponse)
+	}))
+	defer server.Close()
+
+	result, err := testClient(server).Generate(context.Background(), OllamaRequest{Model: model}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Response != mockResponse.Response || requests != 3 {
+		t.Errorf("response %q after %d requests", result.Response, requests)
+	}
+}
+
+func TestClientServerDown(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, err := testClient(server).Generate(context.Background(), OllamaRequest{Model: model}, nil)
+	if !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("err = %v, want ErrServerUnavailable", err)
+	}
+}
+
+func TestClientTimeoutAndCancel(t *testing.T) {
+	stopped := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is read
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		stopped <- struct{}{}
+	}))
+	defer server.Close()
+
+	client := testClient(server)
+	client.Timeout = 50 * time.Millisecond
+	if _, err := client.Generate(context.Background(), OllamaRequest{Model: model}, nil); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want ErrTimeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	client.Timeout = 0
+	if _, err := client.Generate(ctx, OllamaRequest{Model: model}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	// Both requests were abandoned on the server side too
+	for i := 0; i < 2; i++ {
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("request was not cancelled")
+		}
+	}
+}
+
+func TestClientChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) != 2 || req.Messages[0].Role != RoleSystem || req.Messages[1].Content != "hi" {
+			t.Errorf("messages = %+v", req.Messages)
+		}
+
+		encoder := json.NewEncoder(w)
+		for _, text := range []string{"Hel", "lo"} {
+			encoder.Encode(ChatResponse{Message: Message{Role: RoleAssistant, Content: text}})
+		}
+		encoder.Encode(ChatResponse{Done: true, EvalCount: 2})
+	}))
+	defer server.Close()
+
+	client := &Client{ChatEndpoint: server.URL}
+	result, err := client.Chat(context.Background(), ChatRequest{
+		Model:    model,
+		Messages: []Message{{Role: RoleSystem, Content: "Be brief."}, {Role: RoleUser, Content: "hi"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Message != (Message{Role: RoleAssistant, Content: "Hello"}) || result.Final.EvalCount != 2 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestClientListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("method = %s", r.Method)
+		}
+		w.Write([]byte(`{"models":[{"name":"llama3.2:1b","size":1321098329,"details":{"family":"llama","parameter_size":"1.2B"}}]}`))
+	}))
+	defer server.Close()
+
+	models, err := (&Client{TagsEndpoint: server.URL}).ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0].Name != "llama3.2:1b" || models[0].Size != 1321098329 || models[0].Details.Family != "llama" {
+		t.Errorf("models = %+v", models)
+	}
+}
+
+func TestClientPull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PullRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "nope" {
+			w.Write([]byte(`{"status":"pulling manifest"}` + "\n" + `{"error":"pull model manifest: file does not exist"}`))
+			return
+		}
+		encoder := json.NewEncoder(w)
+		encoder.Encode(PullProgress{Status: "pulling manifest"})
+		encoder.Encode(PullProgress{Status: "pulling 74701a8c35f6", Digest: "sha256:74701a8c35f6", Total: 100, Completed: 40})
+		encoder.Encode(PullProgress{Status: "success"})
+	}))
+	defer server.Close()
+
+	client := &Client{PullEndpoint: server.URL, Timeout: time.Nanosecond}
+	var statuses []string
+	if err := client.Pull(context.Background(), "llama3.2:1b", func(p PullProgress) { statuses = append(statuses, p.Status) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 || statuses[2] != "success" {
+		t.Errorf("statuses = %q", statuses)
+	}
+
+	var serverErr *Error
+	if err := client.Pull(context.Background(), "nope", func(PullProgress) {}); !errors.As(err, &serverErr) {
+		t.Errorf("err = %v, want an *Error", err)
+	}
+}
@@ -1,20 +1,156 @@
 package ollamaimplementation
 
 import (
-	"bytes"
-	"encoding/json"
-	"net/http"
+	"context"
+	"fmt"
 )
 
 var OllamaEndpoint = "http://127.0.0.1:11434/api/generate" // The local endpoint for the Ollama API (explicit IPv4 to avoid IPv6 issues)
 // var OllamaEndpoint = "http://localhost:11434/api/generate" // Alternative: localhost
 // var OllamaEndpoint = "http://host.docker.internal:11434" // For Docker
 
+// OllamaChatEndpoint is the endpoint for message-based conversations
+var OllamaChatEndpoint = "http://127.0.0.1:11434/api/chat"
+
+// Model management endpoints: installed models and downloads
+var (
+	OllamaTagsEndpoint = "http://127.0.0.1:11434/api/tags"
+	OllamaPullEndpoint = "http://127.0.0.1:11434/api/pull"
+)
+
 // Struct for request to Ollama API
 type OllamaRequest struct {
-	Prompt  string `json:"prompt"`
-	Model   string `json:"model"`
-	Context []int  `json:"context,omitempty"` // Context to maintain conversation
+	Prompt  string   `json:"prompt"`
+	Model   string   `json:"model"`
+	Context []int    `json:"context,omitempty"` // Context to maintain conversation
+	Options *Options `json:"options,omitempty"`
+}
+
+// Options are generation parameters for a request. Unset fields keep the
+// model's defaults.
+type Options struct {
+	Temperature *float64 `json:"temperature,omitempty"` // 0 always picks the likeliest token
+	Seed        *int     `json:"seed,omitempty"`        // Fixed seeds make sampling repeatable
+	NumCtx      *int     `json:"num_ctx,omitempty"`     // Context window to allocate, in tokens
+	NumPredict  *int     `json:"num_predict,omitempty"` // Most tokens to generate; -1 for no limit
+	Stop        []string `json:"stop,omitempty"`        // Sequences that end generation
+}
+
+// Merge returns a copy of o with the fields set in override replacing its
+// own. Either may be nil; the result is nil only if both are.
+func (o *Options) Merge(override *Options) *Options {
+	if o == nil && override == nil {
+		return nil
+	}
+	merged := Options{}
+	if o != nil {
+		merged = *o
+		merged.Stop = append([]string(nil), o.Stop...)
+	}
+	if override == nil {
+		return &merged
+	}
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.Seed != nil {
+		merged.Seed = override.Seed
+	}
+	if override.NumCtx != nil {
+		merged.NumCtx = override.NumCtx
+	}
+	if override.NumPredict != nil {
+		merged.NumPredict = override.NumPredict
+	}
+	if override.Stop != nil {
+		merged.Stop = append([]string(nil), override.Stop...)
+	}
+	return &merged
+}
+
+// Validate rejects values Ollama would refuse or misbehave with
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %v", *o.Temperature)
+	}
+	if o.NumCtx != nil && *o.NumCtx <= 0 {
+		return fmt.Errorf("num_ctx must be positive, got %d", *o.NumCtx)
+	}
+	if o.NumPredict != nil && *o.NumPredict < -2 {
+		return fmt.Errorf("num_predict must be -2, -1 or a token count, got %d", *o.NumPredict)
+	}
+	return nil
+}
+
+// Roles of the messages in a chat
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a chat
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is a request to the chat endpoint. Messages is the whole
+// conversation so far, oldest first, ending with the new user message.
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Options  *Options  `json:"options,omitempty"`
+}
+
+// ChatResponse is one streamed chunk of a chat reply
+type ChatResponse struct {
+	Model           string  `json:"model"`
+	CreatedAt       string  `json:"created_at"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason,omitempty"`
+	TotalDuration   int64   `json:"total_duration,omitempty"`
+	LoadDuration    int64   `json:"load_duration,omitempty"`
+	PromptEvalCount int     `json:"prompt_eval_count,omitempty"`
+	EvalCount       int     `json:"eval_count,omitempty"`
+	EvalDuration    int64   `json:"eval_duration,omitempty"`
+}
+
+// ModelInfo describes an installed model, as listed by the tags endpoint
+type ModelInfo struct {
+	Name       string       `json:"name"` // e.g. "llama3.2:1b"
+	Model      string       `json:"model"`
+	ModifiedAt string       `json:"modified_at"`
+	Size       int64        `json:"size"` // Bytes on disk
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+type ModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"` // e.g. "1.2B"
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// PullRequest asks the pull endpoint to download a model
+type PullRequest struct {
+	Model  string `json:"model"`
+	Stream bool   `json:"stream"`
+}
+
+// PullProgress is one streamed status line of a download. Total and
+// Completed are set while a layer is downloading.
+type PullProgress struct {
+	Status    string `json:"status"` // e.g. "pulling manifest", "success"
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
 }
 
 // Struct for response from Ollama API
@@ -33,43 +169,22 @@ type OllamaResponse struct {
 	EvalDuration       int64  `json:"eval_duration,omitempty"`
 }
 
+// GetOllamaResponse sends a prompt and returns the complete response and the
+// updated conversation context. It can't be cancelled; use a Client for that.
 func GetOllamaResponse(prompt string, context []int, model string) (string, []int, error) {
-	// Create request payload with the model specified and context
-	requestBody, err := json.Marshal(OllamaRequest{
-		Prompt: prompt,
-		// Model:   "llama3.1",
-		Model:   model,
-		Context: context, // Pass the conversation context
-	})
-	if err != nil {
-		return "", nil, err
-	}
+	return GetOllamaResponseWithOptions(prompt, context, model, nil)
+}
 
-	// Send HTTP POST request to Ollama API
-	resp, err := http.Post(OllamaEndpoint, "application/json", bytes.NewBuffer(requestBody))
+// GetOllamaResponseWithOptions is GetOllamaResponse with sampling options
+func GetOllamaResponseWithOptions(prompt string, conversation []int, model string, options *Options) (string, []int, error) {
+	result, err := NewClient().Generate(context.Background(), OllamaRequest{
+		Prompt:  prompt,
+		Model:   model,
+		Context: conversation, // Pass the conversation context
+		Options: options,
+	}, nil)
 	if err != nil {
 		return "", nil, err
 	}
-	defer resp.Body.Close()
-
-	// Read and accumulate response body in chunks
-	var completeResponse string
-	var updatedContext []int
-	decoder := json.NewDecoder(resp.Body)
-	for decoder.More() {
-		var chunk OllamaResponse
-		if err := decoder.Decode(&chunk); err != nil {
-			return "", nil, err
-		}
-		completeResponse += chunk.Response
-
-		// Capture the updated context from the response
-		updatedContext = chunk.Context
-
-		if chunk.Done {
-			break
-		}
-	}
-
-	return completeResponse, updatedContext, nil
+	return result.Response, result.Context, nil
 }
@@ -76,6 +76,69 @@ func TestGetOllamaResponse(t *testing.T) {
 	}
 }
 
+func TestGetOllamaResponseWithOptions(t *testing.T) {
+	var bodies []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		responseData, _ := json.Marshal(mockResponse)
+		w.Write(responseData)
+	}))
+	defer mockServer.Close()
+
+	originalEndpoint := OllamaEndpoint
+	OllamaEndpoint = mockServer.URL
+	defer func() { OllamaEndpoint = originalEndpoint }()
+
+	temperature, seed := 0.5, 7
+	if _, _, err := GetOllamaResponseWithOptions("Test prompt", nil, model, &Options{Temperature: &temperature, Seed: &seed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := GetOllamaResponse("Test prompt", nil, model); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(bodies[0], `"options":{"temperature":0.5,"seed":7}`) {
+		t.Errorf("options missing from request: %s", bodies[0])
+	}
+	if strings.Contains(bodies[1], `"options"`) {
+		t.Errorf("request without options sent some: %s", bodies[1])
+	}
+}
+
+func TestOptionsMerge(t *testing.T) {
+	temperature, seed, numCtx, override := 0.8, 1, 8192, 0.0
+	base := &Options{Temperature: &temperature, NumCtx: &numCtx, Stop: []string{"<|eot_id|>"}}
+
+	merged := base.Merge(&Options{Temperature: &override, Seed: &seed})
+	if *merged.Temperature != 0 || *merged.Seed != 1 || *merged.NumCtx != 8192 || len(merged.Stop) != 1 {
+		t.Errorf("merged = %+v", merged)
+	}
+	if *base.Temperature != 0.8 || base.Seed != nil {
+		t.Errorf("base modified: %+v", base)
+	}
+
+	merged.Stop[0] = "changed"
+	if base.Stop[0] != "<|eot_id|>" {
+		t.Error("merged options share Stop with the base")
+	}
+	if (*Options)(nil).Merge(nil) != nil {
+		t.Error("merging nothing should give nil")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	hot, zero, negative := 2.5, 0, -3
+	for _, o := range []*Options{{Temperature: &hot}, {NumCtx: &zero}, {NumPredict: &negative}} {
+		if o.Validate() == nil {
+			t.Errorf("expected %+v to be rejected", o)
+		}
+	}
+	if err := (&Options{NumPredict: new(int)}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
 // Test for prompts.
 var promptTestCases = []struct {
 	name          string
@@ -0,0 +1,367 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// UNIFIED DIFFS
+// ============================================================================
+
+// MaxFuzz is how many context lines at either end of a hunk may be ignored
+// when the hunk doesn't match the source as written, as with patch --fuzz
+const MaxFuzz = 2
+
+// ErrNoDiff is returned when there is no unified diff to parse
+var ErrNoDiff = errors.New("no unified diff found")
+
+// FileDiff is the part of a unified diff that changes one file
+type FileDiff struct {
+	OldName string // From the "---" header, empty if there was none
+	NewName string // From the "+++" header
+	Hunks   []Hunk
+}
+
+// Name returns the patched file's name without the a/ or b/ prefix diff
+// tools add, or "" if the diff had no file headers
+func (f FileDiff) Name() string {
+	name := f.NewName
+	if name == "" || name == "/dev/null" {
+		name = f.OldName
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		name = name[2:]
+	}
+	return name
+}
+
+// Hunk is one "@@" section of a diff. Lines keep their ' ', '-' or '+'
+// prefix. The line numbers are only hints: LLMs rarely get them right.
+type Hunk struct {
+	OldStart, OldLines int // 0 if the header had no line numbers
+	NewStart, NewLines int
+	Lines              []string
+}
+
+// start returns the 0-based source line the hunk's header says it begins
+// at. Insertions name the line they follow.
+func (h Hunk) start() int {
+	if h.OldLines == 0 {
+		return h.OldStart
+	}
+	return h.OldStart - 1
+}
+
+// old returns the lines the hunk expects in the source: context and removals
+func (h Hunk) old() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l[0] != '+' {
+			lines = append(lines, l[1:])
+		}
+	}
+	return lines
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse reads a unified diff. It is lenient in the ways LLM output needs:
+// text around the diff, missing file headers, "@@" lines without numbers,
+// wrong line counts and blank context lines stripped of their leading space.
+func Parse(diff string) ([]FileDiff, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+
+	var files []FileDiff
+	var hunk *Hunk
+	current := func() *FileDiff {
+		if len(files) == 0 {
+			files = append(files, FileDiff{})
+		}
+		return &files[len(files)-1]
+	}
+	endHunk := func() {
+		if hunk == nil {
+			return
+		}
+		// Trailing blank lines are more likely separators than context
+		for len(hunk.Lines) > 0 && hunk.Lines[len(hunk.Lines)-1] == " " {
+			hunk.Lines = hunk.Lines[:len(hunk.Lines)-1]
+		}
+		if len(hunk.Lines) > 0 {
+			f := current()
+			f.Hunks = append(f.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			endHunk()
+			files = append(files, FileDiff{OldName: headerName(line), NewName: headerName(lines[i+1])})
+			i++
+		case strings.HasPrefix(line, "@@"):
+			endHunk()
+			hunk = &Hunk{}
+			if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+				hunk.OldStart, hunk.OldLines = headerRange(m[1], m[2])
+				hunk.NewStart, hunk.NewLines = headerRange(m[3], m[4])
+			}
+		case hunk == nil:
+			// Preamble such as "diff --git" or prose
+		case line == "":
+			hunk.Lines = append(hunk.Lines, " ")
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunk.Lines = append(hunk.Lines, line)
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		default:
+			endHunk()
+		}
+	}
+	endHunk()
+
+	var out []FileDiff
+	for _, f := range files {
+		if len(f.Hunks) > 0 {
+			out = append(out, f)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrNoDiff
+	}
+	return out, nil
+}
+
+// headerName returns the file name of a "---" or "+++" line, without the
+// timestamp diff may append after a tab
+func headerName(line string) string {
+	name := strings.TrimSpace(line[4:])
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// headerRange parses the start and count of a hunk header. The count
+// defaults to 1 when omitted.
+func headerRange(start, count string) (int, int) {
+	s, _ := strconv.Atoi(start)
+	n := 1
+	if count != "" {
+		n, _ = strconv.Atoi(count)
+	}
+	return s, n
+}
+
+var fencePattern = regexp.MustCompile("(?s)```([a-zA-Z]*)[^\\n]*\\n(.*?)```")
+
+// Extract finds the diff in an LLM response: the ```diff or ```patch code
+// blocks, otherwise any code block that contains a hunk, otherwise the
+// response from its first header or hunk on. It returns "" if there is none.
+func Extract(response string) string {
+	var tagged, untagged []string
+	for _, m := range fencePattern.FindAllStringSubmatch(response, -1) {
+		switch {
+		case m[1] == "diff" || m[1] == "patch":
+			tagged = append(tagged, m[2])
+		case hasHunk(m[2]):
+			untagged = append(untagged, m[2])
+		}
+	}
+	if len(tagged) > 0 {
+		return strings.Join(tagged, "")
+	}
+	if len(untagged) > 0 {
+		return strings.Join(untagged, "")
+	}
+
+	if !hasHunk(response) {
+		return ""
+	}
+	lines := strings.Split(response, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "@@") {
+			return strings.Join(lines[i:], "\n")
+		}
+	}
+	return ""
+}
+
+func hasHunk(s string) bool {
+	return strings.HasPrefix(s, "@@") || strings.Contains(s, "\n@@")
+}
+
+// ============================================================================
+// APPLYING
+// ============================================================================
+
+// HunkError reports a hunk that could not be placed in the source
+type HunkError struct {
+	Hunk  int    // 1-based, in diff order
+	First string // First line the hunk expected, to help the LLM find it
+}
+
+func (e *HunkError) Error() string {
+	if e.First == "" {
+		return fmt.Sprintf("hunk %d does not match the source", e.Hunk)
+	}
+	return fmt.Sprintf("hunk %d does not match the source (expected %q)", e.Hunk, e.First)
+}
+
+// Apply applies hunks to src in order. A hunk is placed where its context
+// and removed lines match the source, trying the position its header names
+// first and then ever further away. Lines that match only when whitespace is
+// ignored are accepted, keeping the source's version of context lines; if
+// that fails, up to MaxFuzz context lines at either end are dropped.
+func Apply(src string, hunks []Hunk) (string, error) {
+	trailingNewline := strings.HasSuffix(src, "\n")
+	lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	if src == "" {
+		lines = nil
+	}
+
+	offset := 0 // How far earlier hunks moved the source from the header line numbers
+	floor := 0  // Hunks apply in order, so never before the previous one
+	for i, h := range hunks {
+		pos, trimmed, ok := locate(lines, h, floor, offset)
+		if !ok {
+			first := ""
+			if old := h.old(); len(old) > 0 {
+				first = strings.TrimSpace(old[0])
+			}
+			return "", &HunkError{Hunk: i + 1, First: first}
+		}
+
+		replaced, consumed := replacement(lines[pos:], trimmed)
+		lines = append(lines[:pos], append(replaced, lines[pos+consumed:]...)...)
+
+		floor = pos + len(replaced)
+		if h.OldStart > 0 {
+			offset = pos - h.start() + len(replaced) - consumed
+		}
+	}
+
+	out := strings.Join(lines, "\n")
+	if trailingNewline || (src == "" && len(lines) > 0) {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// locate finds where a hunk applies, with as little fuzz as possible. It
+// returns the position and the hunk with any fuzzed context removed.
+func locate(lines []string, h Hunk, floor, offset int) (int, Hunk, bool) {
+	hint := floor
+	if h.OldStart > 0 {
+		hint = h.start() + offset
+	}
+
+	for fuzz := 0; fuzz <= MaxFuzz; fuzz++ {
+		trimmed, ok := trimContext(h, fuzz)
+		if !ok {
+			break
+		}
+		old := trimmed.old()
+		if len(old) == 0 {
+			// Pure insertion without context: trust the header
+			return clamp(hint, floor, len(lines)), trimmed, true
+		}
+		for _, equal := range []func(a, b string) bool{exact, looseEqual} {
+			if pos, ok := search(lines, old, floor, clamp(hint, floor, len(lines)), equal); ok {
+				return pos, trimmed, true
+			}
+		}
+	}
+	return 0, Hunk{}, false
+}
+
+// trimContext drops up to fuzz context lines from each end of a hunk. It
+// reports false if there were fewer than fuzz context lines to drop.
+func trimContext(h Hunk, fuzz int) (Hunk, bool) {
+	lines := h.Lines
+	dropped := false
+	for n := 0; n < fuzz; n++ {
+		if len(lines) > 0 && lines[0][0] == ' ' {
+			lines = lines[1:]
+			dropped = true
+		}
+		if len(lines) > 0 && lines[len(lines)-1][0] == ' ' {
+			lines = lines[:len(lines)-1]
+			dropped = true
+		}
+	}
+	if fuzz > 0 && !dropped {
+		return h, false
+	}
+	h.Lines = lines
+	return h, true
+}
+
+// search looks for old in lines at or after floor, trying positions in
+// order of distance from hint
+func search(lines, old []string, floor, hint int, equal func(a, b string) bool) (int, bool) {
+	last := len(lines) - len(old)
+	for d := 0; hint-d >= floor || hint+d <= last; d++ {
+		for _, pos := range []int{hint - d, hint + d} {
+			if pos >= floor && pos <= last && matches(lines[pos:], old, equal) {
+				return pos, true
+			}
+			if d == 0 {
+				break
+			}
+		}
+	}
+	return 0, false
+}
+
+func matches(lines, old []string, equal func(a, b string) bool) bool {
+	for i, l := range old {
+		if !equal(lines[i], l) {
+			return false
+		}
+	}
+	return true
+}
+
+func exact(a, b string) bool { return a == b }
+
+// looseEqual compares lines ignoring the amount of whitespace
+func looseEqual(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+// replacement returns the lines a hunk puts in place of the source lines it
+// matched, and how many source lines that is. Context lines are taken from
+// the source, so a loose match doesn't change their indentation.
+func replacement(src []string, h Hunk) ([]string, int) {
+	var out []string
+	consumed := 0
+	for _, l := range h.Lines {
+		switch l[0] {
+		case ' ':
+			out = append(out, src[consumed])
+			consumed++
+		case '-':
+			consumed++
+		case '+':
+			out = append(out, l[1:])
+		}
+	}
+	return out, consumed
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
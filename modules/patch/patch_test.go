@@ -0,0 +1,188 @@
+package patch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const source = `package main
+
+import "fmt"
+
+func Add(a, b int) int {
+	return a - b
+}
+
+func main() {
+	fmt.Println(Add(1, 2))
+}
+`
+
+// applyDiff parses diff and applies its only file to src
+func applyDiff(t *testing.T, src, diff string) (string, error) {
+	t.Helper()
+	files, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	return Apply(src, files[0].Hunks)
+}
+
+func TestParse(t *testing.T) {
+	diff := `Here is the fix:
+diff --git a/main.go b/main.go
+--- a/main.go	2024-01-01 00:00:00
++++ b/main.go
+@@ -5,3 +5,3 @@ func Add(a, b int) int {
+ func Add(a, b int) int {
+-	return a - b
++	return a + b
+
+--- a/main_test.go
++++ b/main_test.go
+@@
+-	want := 4
++	want := 3
+\ No newline at end of file
+That should do it.
+`
+	files, err := Parse(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name() != "main.go" || files[1].Name() != "main_test.go" {
+		t.Fatalf("files = %+v", files)
+	}
+	h := files[0].Hunks[0]
+	if h.OldStart != 5 || h.OldLines != 3 || len(h.Lines) != 3 {
+		t.Errorf("hunk = %+v, want 3 lines at 5 without the trailing blank", h)
+	}
+	if h := files[1].Hunks[0]; h.OldStart != 0 || len(h.Lines) != 2 {
+		t.Errorf("hunk without numbers = %+v", h)
+	}
+
+	if _, err := Parse("no diff here"); !errors.Is(err, ErrNoDiff) {
+		t.Errorf("err = %v, want ErrNoDiff", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name, response, want string
+	}{
+		{"tagged block", "Fix:\n```diff\n@@ -1 +1 @@\n-a\n+b\n```\n", "@@ -1 +1 @@\n-a\n+b\n"},
+		{"untagged block", "```\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n```", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n"},
+		{"bare", "Sure.\n@@ -1 +1 @@\n-a\n+b", "@@ -1 +1 @@\n-a\n+b"},
+		{"full code", "```go\npackage main\n```", ""},
+	}
+	for _, tt := range tests {
+		if got := Extract(tt.response); got != tt.want {
+			t.Errorf("%s: Extract = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyExact(t *testing.T) {
+	got, err := applyDiff(t, source, `@@ -5,3 +5,3 @@
+ func Add(a, b int) int {
+-	return a - b
++	return a + b
+ }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(source, "a - b", "a + b", 1); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApplyWrongLineNumbers(t *testing.T) {
+	got, err := applyDiff(t, source, `@@ -40,2 +40,3 @@
+ func main() {
++	fmt.Println("start")
+ 	fmt.Println(Add(1, 2))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "func main() {\n\tfmt.Println(\"start\")\n\tfmt.Println(Add(1, 2))") {
+		t.Errorf("got\n%s", got)
+	}
+}
+
+func TestApplyIgnoresWhitespace(t *testing.T) {
+	// Indented with spaces instead of tabs, as LLMs often do
+	got, err := applyDiff(t, source, `@@ -5,3 +5,3 @@
+ func Add(a, b int) int {
+-    return a - b
++	return a + b
+ }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "func Add(a, b int) int {\n\treturn a + b\n}") {
+		t.Errorf("got\n%s", got)
+	}
+}
+
+func TestApplyFuzz(t *testing.T) {
+	// The first context line was made up; fuzz drops it
+	got, err := applyDiff(t, source, `@@ -4,4 +4,4 @@
+ // Add adds two numbers
+ func Add(a, b int) int {
+-	return a - b
++	return a + b
+ }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "// Add adds") || !strings.Contains(got, "return a + b") {
+		t.Errorf("got\n%s", got)
+	}
+}
+
+func TestApplyMultipleHunks(t *testing.T) {
+	got, err := applyDiff(t, source, `@@ -1,3 +1,6 @@
+ package main
+
+-import "fmt"
++import (
++	"fmt"
++	"os"
++)
+@@ -6,1 +9,1 @@
+-	return a - b
++	return a + b
+@@ -10,1 +13,1 @@
+-	fmt.Println(Add(1, 2))
++	fmt.Fprintln(os.Stdout, Add(1, 2))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"import (\n\t\"fmt\"\n\t\"os\"\n)", "return a + b", "fmt.Fprintln(os.Stdout, Add(1, 2))"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestApplyRejectsMismatch(t *testing.T) {
+	_, err := applyDiff(t, source, `@@ -5,3 +5,3 @@
+ func Subtract(a, b int) int {
+-	return a * b
++	return a - b
+ }
+`)
+	var hunkErr *HunkError
+	if !errors.As(err, &hunkErr) || hunkErr.Hunk != 1 || hunkErr.First != "func Subtract(a, b int) int {" {
+		t.Errorf("err = %v, want a HunkError for hunk 1", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"llama/modules/language"
+	"llama/modules/patch"
+	"path/filepath"
+)
+
+// ============================================================================
+// PATCH MODE
+// ============================================================================
+
+// patchInstructions ask for a unified diff against the code in the repair
+// prompt instead of both files in full
+func patchInstructions(mainFile, testFile string) string {
+	return fmt.Sprintf(`
+Fix the problems above. Do NOT output the complete files: reply with a unified diff against your previous code, in one `+"```diff"+` block.
+Start the changes to each file with "--- a/<file>" and "+++ b/<file>" headers, where <file> is %s or %s, followed by "@@ -line,count +line,count @@" hunks.
+Prefix unchanged lines with a space, removed lines with "-" and added lines with "+". Copy 3 unchanged lines around each change exactly as they appear in your previous code.
+`, mainFile, testFile)
+}
+
+// wantsPatch reports whether the repair prompt should ask for a diff. That
+// takes patch mode, an attempt to patch, and both of its files in the
+// prompt: the LLM can't write context lines for code it can't see.
+func wantsPatch(job *ExecutionJob, iteration int, detail promptDetail) bool {
+	return job.PatchMode && iteration > 1 && job.LLMCtx.LastAttempt() != nil &&
+		detail.showMainCode && detail.showTestCode
+}
+
+// applyPatch applies the diff in an LLM response to the attempt it was
+// asked to repair. It returns the patched code and the diff.
+func applyPatch(lang language.Language, attempt *Attempt, response string) (string, string, string, error) {
+	diff := patch.Extract(response)
+	if diff == "" {
+		return "", "", "", patch.ErrNoDiff
+	}
+	files, err := patch.Parse(diff)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	mainFile, testFile := lang.FileNames()
+	sources := map[string]string{mainFile: attempt.MainCode, testFile: attempt.TestCode}
+	for _, f := range files {
+		// A diff without headers can only mean the main file
+		name := mainFile
+		if f.Name() != "" {
+			name = filepath.Base(f.Name())
+		}
+		src, ok := sources[name]
+		if !ok {
+			return "", "", "", fmt.Errorf("patch changes unknown file %q", f.Name())
+		}
+		patched, err := patch.Apply(src, f.Hunks)
+		if err != nil {
+			return "", "", "", fmt.Errorf("%s: %w", name, err)
+		}
+		sources[name] = patched
+	}
+
+	return sources[mainFile], sources[testFile], diff, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"llama/modules/language/golang"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"llama/modules/patch"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	patchMain = "package main\n\nfunc Add(a, b int) int {\n\treturn a - b\n}\n\nfunc main() {}\n"
+	patchTest = "package main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}\n"
+
+	patchResponse = "Here is the fix:\n```diff\n--- a/main.go\n+++ b/main.go\n@@ -3,3 +3,3 @@\n func Add(a, b int) int {\n-\treturn a - b\n+\treturn a + b\n }\n```\n"
+)
+
+func TestApplyPatch(t *testing.T) {
+	attempt := &Attempt{MainCode: patchMain, TestCode: patchTest}
+
+	mainCode, testCode, diff, err := applyPatch(golang.New(), attempt, patchResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(mainCode, "return a + b") || testCode != patchTest {
+		t.Errorf("patched code:\n%s\n%s", mainCode, testCode)
+	}
+	if !strings.HasPrefix(diff, "--- a/main.go") {
+		t.Errorf("diff = %q", diff)
+	}
+
+	if _, _, _, err := applyPatch(golang.New(), attempt, "```go\n"+patchMain+"```"); !errors.Is(err, patch.ErrNoDiff) {
+		t.Errorf("full code: err = %v, want ErrNoDiff", err)
+	}
+	unknown := strings.ReplaceAll(patchResponse, "main.go", "util.go")
+	if _, _, _, err := applyPatch(golang.New(), attempt, unknown); err == nil || !strings.Contains(err.Error(), "unknown file") {
+		t.Errorf("unknown file: err = %v", err)
+	}
+}
+
+func TestPlanPromptAsksForPatch(t *testing.T) {
+	job := budgetJob(t, patchMain, 0)
+	job.PatchMode = true
+
+	plan := planPrompt(job, 3)
+	if !plan.Patch || !strings.Contains(plan.Prompt, "unified diff") {
+		t.Fatalf("plan.Patch = %v, prompt:\n%s", plan.Patch, plan.Prompt)
+	}
+	if strings.Contains(plan.Fallback, "unified diff") || !strings.Contains(plan.Fallback, "complete corrected code") {
+		t.Errorf("fallback should ask for the full code:\n%s", plan.Fallback)
+	}
+
+	if plan := planPrompt(job, 1); plan.Patch {
+		t.Error("the first prompt has nothing to patch")
+	}
+	job.PatchMode = false
+	if plan := planPrompt(job, 3); plan.Patch || plan.Fallback != "" {
+		t.Error("patch requested without patch mode")
+	}
+}
+
+func TestRunCandidatePatchFallback(t *testing.T) {
+	var requests int32
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A diff that doesn't match, then the full code
+		response := "```diff\n@@ -1,1 +1,1 @@\n-func Subtract() {}\n+func Sub() {}\n```"
+		if atomic.AddInt32(&requests, 1) > 1 {
+			response = "```go\n" + strings.Replace(patchMain, "a - b", "a + b", 1) + "```\n```go\n" + patchTest + "```"
+		}
+		json.NewEncoder(w).Encode(chatReply(response))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	job := recoveryJob(t, seedLang{golang.New()})
+	job.LLMCtx.Attempts = []Attempt{{Iteration: 1, MainCode: patchMain, TestCode: patchTest, Result: &CompilationResult{ErrorType: ErrorTypeLogic}}}
+
+	c := runCandidate(job, &recordingSink{}, 1, 0, promptPlan{Prompt: "diff please", Patch: true, Fallback: "full code please"}, nil)
+	if c.AbortCode != "" {
+		t.Fatalf("candidate failed: %s", c.Err)
+	}
+	if requests != 2 || c.Patch != "" || !strings.Contains(c.PatchError, "hunk 1") {
+		t.Errorf("requests %d, patch %q, patch error %q; want a fallback after a failed hunk", requests, c.Patch, c.PatchError)
+	}
+	if !strings.Contains(c.MainCode, "return a + b") {
+		t.Errorf("main code:\n%s", c.MainCode)
+	}
+}
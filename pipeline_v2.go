@@ -3,12 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
-	"llama/modules/extraction"
-	ollamaimplementation "llama/modules/ollama-implementation"
 	"strings"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
 // ============================================================================
@@ -16,45 +12,40 @@ import (
 // ============================================================================
 
 // RunCompilationJob executes the production compiler pipeline with safeguards
-func RunCompilationJob(job *ExecutionJob, conn *websocket.Conn) {
+func RunCompilationJob(job *ExecutionJob, sink MessageSink) {
 	defer func() {
-		job.Status = "completed"
 		if r := recover(); r != nil {
 			fmt.Printf("[PANIC] Job %s: %v\n", job.ID, r)
-			job.AbortReason = "internal_panic"
+			job.finish("aborted", "internal_panic")
+			sendAbortMessage(sink, job, fmt.Sprintf("Internal error: %v", r))
+		}
+		if job.Recorder != nil {
+			finishRecording(job, sink)
 		}
+		// Release the job context's resources once the pipeline is done
+		job.Cancel()
 	}()
 
-	job.Status = "running"
-	job.StartTime = time.Now()
-
-	extractor := extraction.NewExtractor()
+	job.start()
 
 	// ============================================================================
 	// MAIN ITERATION LOOP WITH SAFEGUARDS
 	// ============================================================================
 
 	for iteration := 1; iteration <= job.MaxIterations; iteration++ {
-		select {
-		case <-job.Ctx.Done():
-			// User cancelled
-			job.Status = "aborted"
-			job.AbortReason = "user_cancelled"
-			sendAbortMessage(conn, job, "User cancelled execution")
+		if job.Ctx.Err() != nil {
+			abortForContext(sink, job)
 			return
-
-		default:
 		}
 
 		// Check total timeout
 		if time.Since(job.StartTime) > job.Timeout {
-			job.Status = "aborted"
-			job.AbortReason = "total_timeout"
-			sendAbortMessage(conn, job, "Total timeout exceeded")
+			job.finish("aborted", "total_timeout")
+			sendAbortMessage(sink, job, "Total timeout exceeded")
 			return
 		}
 
-		job.Metrics.IterationCount = iteration
+		job.setIteration(iteration)
 
 		fmt.Printf("[Job %s] Iteration %d/%d started\n", job.ID, iteration, job.MaxIterations)
 
@@ -64,82 +55,85 @@ func RunCompilationJob(job *ExecutionJob, conn *websocket.Conn) {
 
 		fmt.Printf("[Job %s] Phase 1: Generating code...\n", job.ID)
 
-		prompt, promptSize := buildPrompt(job, iteration)
+		plan := planPrompt(job, iteration)
+		prompt, promptSize := plan.Prompt, len(plan.Prompt)
+
+		if plan.ResetContext {
+			fmt.Printf("[Job %s] Context budget nearly used up, continuing from a compact history (%s detail)\n", job.ID, plan.Detail)
+			job.setMessages(nil)
+			job.LLMCtx.ContextResets++
+		}
 
 		// Check prompt size growth
 		if promptSize > int(MaxPromptSize) {
-			job.Status = "aborted"
-			job.AbortReason = "prompt_size_exceeded"
-			sendAbortMessage(conn, job, fmt.Sprintf("Prompt size exceeded: %d > %d bytes", promptSize, MaxPromptSize))
+			job.finish("aborted", "prompt_size_exceeded")
+			sendAbortMessage(sink, job, fmt.Sprintf("Prompt size exceeded: %d > %d bytes", promptSize, MaxPromptSize))
 			return
 		}
 
-		job.Metrics.PromptSizes = append(job.Metrics.PromptSizes, promptSize)
-
-		// LLM Call with timeout
-		llmStart := time.Now()
-		// Note: GetOllamaResponse doesn't currently support context timeout
-		// TODO: Add context support to ollama module
-		llmResponse, updatedContext, llmErr := ollamaimplementation.GetOllamaResponse(prompt, job.LLMCtx.ConversationTokens, job.Model)
-		llmTime := time.Since(llmStart)
-
-		job.Metrics.LLMResponseTimes = append(job.Metrics.LLMResponseTimes, llmTime)
-
-		if llmErr != nil {
-			fmt.Printf("[Job %s] LLM error: %v\n", job.ID, llmErr)
-			job.Status = "aborted"
-			job.AbortReason = "llm_error"
-			sendAbortMessage(conn, job, fmt.Sprintf("LLM error: %v", llmErr))
+		if budget := contextBudget(job.Model, job.Options); plan.Tokens() > budget {
+			job.finish("aborted", "context_budget_exceeded")
+			sendAbortMessage(sink, job, fmt.Sprintf("Prompt does not fit the context of %s even when compacted: ~%d > %d tokens", job.Model, plan.Tokens(), budget))
 			return
 		}
 
-		if llmResponse == "" {
-			fmt.Printf("[Job %s] Empty LLM response\n", job.ID)
-			job.Status = "aborted"
-			job.AbortReason = "empty_llm_response"
-			sendAbortMessage(conn, job, "LLM returned empty response")
+		if growth := promptGrowth(job, iteration, plan.PromptTokens); growth > MaxPromptSizeGrowthRate {
+			job.finish("aborted", "prompt_growth_exceeded")
+			sendAbortMessage(sink, job, fmt.Sprintf("Prompt grew %.1fx since the last iteration even when compacted (limit %.1fx)", growth, MaxPromptSizeGrowthRate))
 			return
 		}
 
-		job.LLMCtx.ConversationTokens = updatedContext
-		job.LLMCtx.PromptHistory = append(job.LLMCtx.PromptHistory, prompt)
+		job.Metrics.PromptSizes = append(job.Metrics.PromptSizes, promptSize)
+		job.Metrics.PromptTokens = append(job.Metrics.PromptTokens, plan.PromptTokens)
 
 		// ======================================================================
-		// PHASE 2: EXTRACT CODE FROM LLM RESPONSE
+		// PHASES 2-3: EXTRACT, COMPILE AND TEST EACH CANDIDATE
 		// ======================================================================
 
-		fmt.Printf("[Job %s] Phase 2: Extracting code...\n", job.ID)
+		fmt.Printf("[Job %s] Phases 2-3: Extracting, compiling and testing %d candidate(s)...\n", job.ID, job.Candidates)
 
-		mainCode, testCode, extractionStrategy := extractor.ExtractWithFallback(llmResponse)
+		candidates := runCandidates(job, sink, iteration, plan)
 
-		if mainCode == "" {
-			fmt.Printf("[Job %s] Extraction failed, no code recovered\n", job.ID)
-			job.Status = "aborted"
-			job.AbortReason = "extraction_failed"
-			sendAbortMessage(conn, job, "Failed to extract code from LLM response")
+		if job.Ctx.Err() != nil {
+			abortForContext(sink, job)
 			return
 		}
 
-		fmt.Printf("[Job %s] Extraction strategy: %s\n", job.ID, extractionStrategy)
+		best := bestCandidate(candidates)
+		if best == nil {
+			// No candidate got as far as compiling; report why the first didn't
+			failed := candidates[0]
+			job.Metrics.LLMResponseTimes = append(job.Metrics.LLMResponseTimes, failed.LLMTime)
+			job.finish("aborted", failed.AbortCode)
+			sendAbortMessage(sink, job, failed.Err)
+			return
+		}
 
-		// ======================================================================
-		// PHASE 3: COMPILE AND TEST
-		// ======================================================================
+		if len(candidates) > 1 {
+			fmt.Printf("[Job %s] Carrying forward candidate %d (errorType=%s)\n", job.ID, best.Index, best.Result.ErrorType)
+		}
 
-		fmt.Printf("[Job %s] Phase 3: Compiling and testing...\n", job.ID)
+		job.Metrics.LLMResponseTimes = append(job.Metrics.LLMResponseTimes, best.LLMTime)
+		job.setMessages(best.Messages)
+		job.LLMCtx.PromptHistory = append(job.LLMCtx.PromptHistory, prompt)
+		job.recordAutoFixes(best.AutoFixes)
+		job.recordPatch(best)
 
-		compileCtx, cancel := context.WithTimeout(job.Ctx, DefaultCompileTimeout)
-		result, compileErr := compileLanguage(compileCtx, job.Language, mainCode, testCode)
-		cancel()
+		mainCode, testCode, result := best.MainCode, best.TestCode, best.Result
 
-		if compileErr != nil {
-			fmt.Printf("[Job %s] Compilation error: %v\n", job.ID, compileErr)
-			job.Status = "aborted"
-			job.AbortReason = "compilation_failed"
-			sendAbortMessage(conn, job, fmt.Sprintf("Compilation failed: %v", compileErr))
+		if job.Ctx.Err() != nil {
+			abortForContext(sink, job)
 			return
 		}
 
+		// Infrastructure errors aren't the code's fault: try to get past
+		// them without going back to the LLM
+		if result.ErrorType == ErrorTypeInfrastructure {
+			result = recoverInfrastructure(job, sink, iteration, mainCode, testCode, result)
+			best.Result, best.Score = result, scoreResult(result)
+		}
+		job.recordSolution(iteration, best)
+
 		// ======================================================================
 		// PHASE 4: ANALYZE RESULTS
 		// ======================================================================
@@ -147,14 +141,14 @@ func RunCompilationJob(job *ExecutionJob, conn *websocket.Conn) {
 		fmt.Printf("[Job %s] Phase 4: Analyzing results (success=%v, errorType=%s)\n", job.ID, result.Success, result.ErrorType.String())
 
 		// Send iteration result
-		sendIterationMessage(conn, job, iteration, mainCode, testCode, result)
+		sendIterationMessage(sink, job, iteration, best, len(candidates))
 
 		// Check if successful
 		if result.Success {
 			fmt.Printf("[Job %s] ✓ SUCCESS on iteration %d\n", job.ID, iteration)
-			job.FinalResult = result
-			job.Status = "completed"
-			sendCompletionMessage(conn, job, mainCode, testCode)
+			job.setFinalResult(result)
+			job.finish("completed", "")
+			sendCompletionMessage(sink, job, mainCode, testCode)
 			return
 		}
 
@@ -166,51 +160,50 @@ func RunCompilationJob(job *ExecutionJob, conn *websocket.Conn) {
 
 		// Update error tracking
 		job.LLMCtx.ErrorHistory = append(job.LLMCtx.ErrorHistory, result.ErrorType)
-		job.LLMCtx.LastErrorMessage = strings.Join(result.CompileErrors, "; ")
-		job.Metrics.LastErrorType = result.ErrorType
-
-		// Check for infrastructure errors (don't feed to LLM)
+		job.LLMCtx.LastErrorMessage = strings.Join(append(append([]string{}, result.CompileErrors...), result.TestErrors...), "; ")
+		job.LLMCtx.Attempts = append(job.LLMCtx.Attempts, Attempt{
+			Iteration: iteration,
+			MainCode:  mainCode,
+			TestCode:  testCode,
+			Result:    result,
+
+			Fingerprint: errorFingerprint(result),
+			ErrorCount:  errorCount(result),
+		})
+		job.recordError(result)
+
+		// Recovery didn't help. If imports couldn't be resolved the LLM can
+		// still avoid them; anything else is beyond its reach.
 		if result.ErrorType == ErrorTypeInfrastructure {
-			fmt.Printf("[Job %s] Infrastructure error detected, retrying without LLM\n", job.ID)
-			// TODO: Implement infrastructure error recovery (e.g., clean and retry)
-			// For now, we can try once more
-			if iteration < 2 {
-				continue // Retry loop
+			if needsStdlibOnly(job, result) && iteration < job.MaxIterations {
+				fmt.Printf("[Job %s] Imports can't be resolved, asking for standard library code only\n", job.ID)
+				job.StdlibOnly = true
+				sendRecoveryMessage(sink, iteration, "stdlib_only", nil, "Imports could not be resolved; regenerating with the standard library only")
+				continue
 			}
-			job.Status = "aborted"
-			job.AbortReason = "infrastructure_error_persistent"
-			sendAbortMessage(conn, job, "Persistent infrastructure error")
+			fmt.Printf("[Job %s] Infrastructure error persists after recovery\n", job.ID)
+			job.finish("aborted", "infrastructure_error_persistent")
+			sendAbortMessage(sink, job, "Persistent infrastructure error: "+firstError(result))
 			return
 		}
 
-		// Check for same error repeating (LLM stuck)
-		if len(job.LLMCtx.ErrorHistory) >= SameErrorThreshold {
-			lastErrors := job.LLMCtx.ErrorHistory[len(job.LLMCtx.ErrorHistory)-SameErrorThreshold:]
-			allSame := true
-			for _, e := range lastErrors {
-				if e != result.ErrorType {
-					allSame = false
-					break
-				}
-			}
-
-			if allSame && result.ErrorType != ErrorTypeSuccess {
-				fmt.Printf("[Job %s] Same error %d times, LLM stuck\n", job.ID, SameErrorThreshold)
-				job.Status = "aborted"
-				job.AbortReason = "same_error_threshold"
-				job.Metrics.SameErrorCount = SameErrorThreshold
-				sendAbortMessage(conn, job, fmt.Sprintf("LLM stuck with same error after %d attempts", SameErrorThreshold))
-				return
-			}
+		// Check whether the LLM is stuck: repeating itself, going in
+		// circles, or not getting any further
+		if stuck, ok := detectStuck(job.LLMCtx.Attempts); ok {
+			fmt.Printf("[Job %s] LLM stuck (%s): %s\n", job.ID, stuck.Code, stuck.Detail)
+			job.Metrics.SameErrorCount = trailingRepeats(job.LLMCtx.Attempts)
+			job.StuckReason = stuck.Detail
+			job.finish("aborted", stuck.Code)
+			sendAbortMessage(sink, job, "LLM stuck: "+stuck.Detail)
+			return
 		}
 
 		// Check iteration limit
 		if iteration >= job.MaxIterations {
 			fmt.Printf("[Job %s] Max iterations reached (%d)\n", job.ID, job.MaxIterations)
-			job.Status = "aborted"
-			job.AbortReason = "max_iterations_reached"
-			job.FinalResult = result
-			sendAbortMessage(conn, job, fmt.Sprintf("Max iterations (%d) reached", job.MaxIterations))
+			job.setFinalResult(result)
+			job.finish("aborted", "max_iterations_reached")
+			sendAbortMessage(sink, job, fmt.Sprintf("Max iterations (%d) reached", job.MaxIterations))
 			return
 		}
 
@@ -218,8 +211,7 @@ func RunCompilationJob(job *ExecutionJob, conn *websocket.Conn) {
 	}
 
 	// Should not reach here
-	job.Status = "completed"
-	job.AbortReason = "unknown"
+	job.finish("completed", "unknown")
 }
 
 // ============================================================================
@@ -228,98 +220,117 @@ func RunCompilationJob(job *ExecutionJob, conn *websocket.Conn) {
 
 // buildPrompt constructs the prompt for the LLM, including error feedback
 func buildPrompt(job *ExecutionJob, iteration int) (string, int) {
+	prompt := composePrompt(job, iteration, detailFull, false, wantsPatch(job, iteration, detailFull))
+	return prompt, len(prompt)
+}
+
+// systemMessage is the system prompt of the job's conversations: the job's
+// system prompt followed by the language's format instructions
+func systemMessage(job *ExecutionJob) string {
+	return strings.TrimSpace(job.SystemPrompt) + "\n\n" + strings.TrimSpace(job.Lang.FormatInstructions())
+}
+
+// composePrompt assembles the user prompt and, after the first iteration,
+// feedback on the last attempt at the given detail. The format instructions
+// are in the system message. withHistory adds a summary of the earlier attempts for when the
+// conversation context is not sent along; patch asks for a diff against the
+// last attempt rather than the full code.
+func composePrompt(job *ExecutionJob, iteration int, detail promptDetail, withHistory, patch bool) string {
 	var prompt strings.Builder
 
 	// Initial prompt
 	prompt.WriteString(job.UserPrompt)
 
-	// Add language-specific format instructions
-	switch job.Language {
-	case "go":
-		prompt.WriteString(goFormatInstructions)
-	case "python":
-		prompt.WriteString(pythonFormatInstructions)
-	case "cpp":
-		prompt.WriteString(cppFormatInstructions)
+	if job.StdlibOnly {
+		prompt.WriteString(stdlibOnlyInstructions)
 	}
 
 	// Add error feedback if not first iteration
-	if iteration > 1 && len(job.LLMCtx.ErrorHistory) > 0 {
-		lastError := job.Metrics.LastErrorType
-		lastMsg := job.LLMCtx.LastErrorMessage
-
-		prompt.WriteString("\n\n=== ERROR FEEDBACK FROM ITERATION ")
-		prompt.WriteString(fmt.Sprintf("%d", iteration-1))
-		prompt.WriteString(" ===\n")
-		prompt.WriteString(fmt.Sprintf("Error Type: %s\n", lastError.String()))
-		prompt.WriteString(fmt.Sprintf("Error Message: %s\n", truncate(lastMsg, 500)))
-		prompt.WriteString("Please fix the error and regenerate the code.\n")
+	if attempt := job.LLMCtx.LastAttempt(); iteration > 1 && attempt != nil {
+		if withHistory {
+			prompt.WriteString(attemptHistory(job.LLMCtx.Attempts))
+		}
+		prompt.WriteString(buildRepairPrompt(job.Lang, attempt, detail, patch))
 	}
 
-	promptSize := len(prompt.String())
-	return prompt.String(), promptSize
+	return prompt.String()
 }
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// abortForContext ends a job whose context is done: either its total timeout
+// ran out or the user cancelled it
+func abortForContext(sink MessageSink, job *ExecutionJob) {
+	if job.Ctx.Err() == context.DeadlineExceeded {
+		job.finish("aborted", "total_timeout")
+		sendAbortMessage(sink, job, "Total timeout exceeded")
+		return
 	}
-	return s[:maxLen] + "..."
-}
 
-// compileLanguage delegates to the appropriate language compiler
-func compileLanguage(ctx context.Context, language, mainCode, testCode string) (*CompilationResult, error) {
-	switch language {
-	case "go":
-		return compileGo(ctx, mainCode, testCode)
-	case "python":
-		return compilePython(ctx, mainCode, testCode)
-	case "cpp":
-		return compileCPP(ctx, mainCode, testCode)
-	default:
-		return nil, fmt.Errorf("unsupported language: %s", language)
-	}
+	// User cancelled
+	job.finish("aborted", "user_cancelled")
+	sendAbortMessage(sink, job, "User cancelled execution")
 }
 
-// ============================================================================
-// LANGUAGE-SPECIFIC COMPILERS (stubs - to be implemented)
-// ============================================================================
-
-func compileGo(ctx context.Context, mainCode, testCode string) (*CompilationResult, error) {
-	// TODO: Implement Go compilation with proper error classification
-	// This should use modules/compiler_v2/go_compiler_v2 with improvements:
-	// - Fresh temp directory each iteration
-	// - Timeout enforcement
-	// - Proper error parsing
-	return nil, fmt.Errorf("not yet implemented")
+// compileTimeout caps DefaultCompileTimeout by what is left of the job's total timeout
+// classifyResult has the job's backend decide what kind of failure result
+// is, which is what the pipeline acts on. What Compile reported stands when
+// the backend can't tell from the result alone.
+func classifyResult(job *ExecutionJob, result *CompilationResult) {
+	if result.Success {
+		return
+	}
+	if errorType := job.Lang.ClassifyError(result); errorType != ErrorTypeUnknown {
+		result.ErrorType = errorType
+	}
 }
 
-func compilePython(ctx context.Context, mainCode, testCode string) (*CompilationResult, error) {
-	// TODO: Implement Python compilation
-	return nil, fmt.Errorf("not yet implemented")
+func compileTimeout(job *ExecutionJob) time.Duration {
+	remaining := job.Timeout - time.Since(job.StartTime)
+	if remaining < DefaultCompileTimeout {
+		return remaining
+	}
+	return DefaultCompileTimeout
 }
 
-func compileCPP(ctx context.Context, mainCode, testCode string) (*CompilationResult, error) {
-	// TODO: Implement C++ compilation
-	return nil, fmt.Errorf("not yet implemented")
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
 }
 
 // ============================================================================
 // WEBSOCKET MESSAGE SENDERS
 // ============================================================================
 
-func sendIterationMessage(conn *websocket.Conn, job *ExecutionJob, iteration int, mainCode, testCode string, result *CompilationResult) {
+func sendIterationMessage(sink MessageSink, job *ExecutionJob, iteration int, c *candidate, candidates int) {
+	result := c.Result
 	data := WSIterationData{
 		Iteration:            iteration,
 		Status:               "compiled",
-		MainCode:             mainCode,
-		TestCode:             testCode,
+		MainCode:             c.MainCode,
+		TestCode:             c.TestCode,
 		CompilerOutput:       result.Output,
 		CompiledSuccessfully: result.Success,
 		ErrorType:            result.ErrorType.String(),
+		ErrorSubtype:         result.ErrorSubtype,
+		Stage:                result.Stage,
 		ElapsedSeconds:       int(time.Since(job.StartTime).Seconds()),
 		PromptSize:           job.Metrics.PromptSizes[len(job.Metrics.PromptSizes)-1],
+		PromptTokens:         job.Metrics.PromptTokens[len(job.Metrics.PromptTokens)-1],
 		LLMResponseTime:      int(job.Metrics.LLMResponseTimes[len(job.Metrics.LLMResponseTimes)-1].Milliseconds()),
+		Diagnostics:          result.Diagnostics,
+		Stages:               result.Stages,
+		Tests:                result.Tests,
+		AutoFixes:            c.AutoFixes,
+		Score:                &c.Score,
+		Patch:                c.Patch,
+		PatchError:           c.PatchError,
+	}
+	if candidates > 1 {
+		data.Candidate, data.Candidates = c.Index, candidates
+	}
+	if !result.Success {
+		data.Fingerprint = errorFingerprint(result)
 	}
 
 	msg := WSMessage{
@@ -327,16 +338,17 @@ func sendIterationMessage(conn *websocket.Conn, job *ExecutionJob, iteration int
 		Data: data,
 	}
 
-	conn.WriteJSON(msg)
+	sink.Send(msg)
 }
 
-func sendCompletionMessage(conn *websocket.Conn, job *ExecutionJob, mainCode, testCode string) {
+func sendCompletionMessage(sink MessageSink, job *ExecutionJob, mainCode, testCode string) {
 	data := WSCompletionData{
 		FinalStatus:     "success",
 		TotalIterations: job.Metrics.IterationCount,
 		TotalTime:       time.Since(job.StartTime).String(),
 		Code:            mainCode,
 		Tests:           testCode,
+		Best:            job.bestSolution(),
 	}
 
 	msg := WSMessage{
@@ -344,10 +356,10 @@ func sendCompletionMessage(conn *websocket.Conn, job *ExecutionJob, mainCode, te
 		Data: data,
 	}
 
-	conn.WriteJSON(msg)
+	sink.Send(msg)
 }
 
-func sendAbortMessage(conn *websocket.Conn, job *ExecutionJob, reason string) {
+func sendAbortMessage(sink MessageSink, job *ExecutionJob, reason string) {
 	lastErrorType := ErrorTypeUnknown.String()
 	if len(job.LLMCtx.ErrorHistory) > 0 {
 		lastErrorType = job.LLMCtx.ErrorHistory[len(job.LLMCtx.ErrorHistory)-1].String()
@@ -358,6 +370,8 @@ func sendAbortMessage(conn *websocket.Conn, job *ExecutionJob, reason string) {
 		Iteration:     job.Metrics.IterationCount,
 		LastError:     reason,
 		LastErrorType: lastErrorType,
+		StuckReason:   job.StuckReason,
+		Best:          job.bestSolution(),
 	}
 
 	msg := WSMessage{
@@ -365,35 +379,5 @@ func sendAbortMessage(conn *websocket.Conn, job *ExecutionJob, reason string) {
 		Data: data,
 	}
 
-	conn.WriteJSON(msg)
+	sink.Send(msg)
 }
-
-// ============================================================================
-// LANGUAGE-SPECIFIC FORMAT INSTRUCTIONS
-// ============================================================================
-
-const goFormatInstructions = `
-IMPORTANT: Generate Go code in this exact format:
-- Two code blocks separated by a blank line
-- First block: package main with main() function and helper functions
-- Second block: package main with import "testing" and Test* functions
-- Use ONLY "package main" in both blocks
-- Provide code only, no explanations
-`
-
-const pythonFormatInstructions = `
-IMPORTANT: Generate Python code in this exact format:
-- Two code blocks separated by a blank line
-- First block: main code with functions and main() execution
-- Second block: import unittest or pytest and test functions
-- Provide code only, no explanations
-`
-
-const cppFormatInstructions = `
-IMPORTANT: Generate C++ code in this exact format:
-- Two code blocks separated by a blank line
-- First block: main.cpp with function implementations and main()
-- Second block: test functions using assert or gtest
-- Include necessary #include statements
-- Provide code only, no explanations
-`
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"llama/modules/language"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCompileTimeoutCappedByJob(t *testing.T) {
+	job := &ExecutionJob{Timeout: 10 * time.Second, StartTime: time.Now()}
+	if got := compileTimeout(job); got > 10*time.Second {
+		t.Errorf("compileTimeout = %v, want <= 10s", got)
+	}
+
+	job.Timeout = time.Hour
+	if got := compileTimeout(job); got != DefaultCompileTimeout {
+		t.Errorf("compileTimeout = %v, want %v", got, DefaultCompileTimeout)
+	}
+}
+
+func TestNewExecutionJobResolvesLanguage(t *testing.T) {
+	for _, name := range []string{"go", "python", "cpp", "GO", ""} {
+		job, err := newExecutionJob(CompileRequest{Language: name, Prompt: "add two numbers"})
+		if err != nil {
+			t.Errorf("newExecutionJob(%q) returned error: %v", name, err)
+			continue
+		}
+		if job.Lang == nil || job.Lang.Name() != job.Language {
+			t.Errorf("newExecutionJob(%q) resolved language %q", name, job.Language)
+		}
+		job.Cancel()
+	}
+
+	_, err := newExecutionJob(CompileRequest{Language: "cobol", Prompt: "add two numbers"})
+	if err == nil || !strings.Contains(err.Error(), "cpp, go, python") {
+		t.Errorf("expected unsupported language error listing backends, got %v", err)
+	}
+}
+
+func TestBuildPromptUsesLanguageInstructions(t *testing.T) {
+	job, err := newExecutionJob(CompileRequest{Language: "python", Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+
+	system := systemMessage(job)
+	if !strings.HasPrefix(system, systemPrompt) || !strings.Contains(system, "test_main.py") {
+		t.Errorf("system message missing python format instructions:\n%s", system)
+	}
+	prompt, size := buildPrompt(job, 1)
+	if strings.Contains(prompt, "test_main.py") || size != len(prompt) {
+		t.Errorf("format instructions repeated in the prompt:\n%s", prompt)
+	}
+}
+
+func TestBuildPromptIncludesRepairFeedback(t *testing.T) {
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+
+	job.LLMCtx.Attempts = []Attempt{{
+		Iteration: 1,
+		MainCode:  "package main\n\nfunc Add(a, b int) int {\n\treturn a + c\n}\n",
+		Result: &CompilationResult{
+			ErrorType:    ErrorTypeType,
+			ErrorSubtype: "undefined",
+			Stage:        "build",
+			Diagnostics: []Diagnostic{
+				{File: "main.go", Line: 4, Column: 13, Message: "undefined: c", Phase: "build"},
+			},
+		},
+	}}
+
+	if prompt, _ := buildPrompt(job, 1); strings.Contains(prompt, "ERROR FEEDBACK") {
+		t.Errorf("first iteration should not carry feedback:\n%s", prompt)
+	}
+
+	prompt, size := buildPrompt(job, 2)
+	for _, want := range []string{
+		"add two numbers",
+		"=== ERROR FEEDBACK FROM ITERATION 1 ===",
+		"Failed Stage: build",
+		subtypeHints["undefined"],
+		"--- main.go ---\n```go\npackage main",
+		"main.go:4:13: undefined: c\n",
+		"> 4 | \treturn a + c\n",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if size != len(prompt) {
+		t.Errorf("size = %d, want %d", size, len(prompt))
+	}
+}
+
+func TestRecordErrorCountsSubtypes(t *testing.T) {
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+
+	job.recordError(&CompilationResult{ErrorType: ErrorTypeType, ErrorSubtype: "undefined"})
+	job.recordError(&CompilationResult{ErrorType: ErrorTypeType, ErrorSubtype: "undefined"})
+	job.recordError(&CompilationResult{ErrorType: ErrorTypeLogic})
+
+	data := job.snapshot().Data.(jobStatusData)
+	if data.ErrorSubtypes["undefined"] != 2 || len(data.ErrorSubtypes) != 1 {
+		t.Errorf("ErrorSubtypes = %v", data.ErrorSubtypes)
+	}
+	if job.Metrics.LastErrorType != ErrorTypeLogic {
+		t.Errorf("LastErrorType = %s", job.Metrics.LastErrorType)
+	}
+}
+
+func TestRecordAutoFixes(t *testing.T) {
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+
+	_, _, fixes := job.Lang.(language.AutoFixer).AutoFix("package main\n\nimport \"os\"\n\nfunc main() {}\n", "")
+	job.recordAutoFixes(fixes)
+
+	data := job.snapshot().Data.(jobStatusData)
+	if data.AutoFixes["remove_unused_import"] != 1 {
+		t.Errorf("AutoFixes = %v", data.AutoFixes)
+	}
+}
+
+// versions are the code the LLM writes in successive iterations
+var versions = []string{"alpha", "beta", "gamma"}
+
+// versionLang passes some of the tests depending on which version of the
+// code it is given: "beta" passes the most
+type versionLang struct {
+	language.Language
+}
+
+func (versionLang) Compile(ctx context.Context, mainCode, testCode string) (*CompilationResult, error) {
+	passed := map[string]int{"alpha": 1, "beta": 2, "gamma": 0}
+	failures := map[string]string{"alpha": "got 2, want 3", "beta": "index out of range", "gamma": "division by zero"}
+	for version, n := range passed {
+		if strings.Contains(mainCode, version) {
+			return &CompilationResult{
+				ErrorType:  ErrorTypeLogic,
+				TestErrors: []string{failures[version]},
+				Tests:      &TestReport{Passed: n, Failed: 3 - n},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected code %q", mainCode)
+}
+
+// chatReply is a complete, unstreamed /api/chat response
+func chatReply(content string) ollamaimplementation.ChatResponse {
+	return ollamaimplementation.ChatResponse{
+		Message: ollamaimplementation.Message{Role: ollamaimplementation.RoleAssistant, Content: content},
+		Done:    true,
+	}
+}
+
+func TestRunCompilationJobReturnsBest(t *testing.T) {
+	var requests int32
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		code := fmt.Sprintf("```go\npackage main\n\n// %s\nfunc main() {}\n```", versions[(n-1)%3])
+		json.NewEncoder(w).Encode(chatReply(code))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", MaxIterations: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Lang = versionLang{job.Lang}
+	sink := &recordingSink{}
+
+	RunCompilationJob(job, sink)
+
+	aborts := sink.ofType(WSTypeAbort)
+	if len(aborts) != 1 {
+		t.Fatalf("got %d abort messages, want 1", len(aborts))
+	}
+	data := aborts[0].Data.(WSAbortData)
+	if data.Reason != "max_iterations_reached" {
+		t.Errorf("Reason = %s", data.Reason)
+	}
+	if data.Best == nil || data.Best.Iteration != 2 || !strings.Contains(data.Best.MainCode, "beta") {
+		t.Fatalf("Best = %+v, want iteration 2", data.Best)
+	}
+	if data.Best.Score.TestsPassed != 2 || data.Best.Score.TestsTotal != 3 {
+		t.Errorf("Best.Score = %+v", data.Best.Score)
+	}
+
+	for i, msg := range sink.ofType(WSTypeIteration) {
+		if msg.Data.(WSIterationData).Score == nil {
+			t.Errorf("iteration %d was not scored", i+1)
+		}
+	}
+}
+
+func TestRunCompilationJobCarriesConversation(t *testing.T) {
+	var requests [][]ollamaimplementation.Message
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaimplementation.ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req.Messages)
+		code := fmt.Sprintf("```go\npackage main\n\n// %s\nfunc main() {}\n```", versions[len(requests)-1])
+		json.NewEncoder(w).Encode(chatReply(code))
+	}))
+	defer llmServer.Close()
+
+	originalEndpoint := ollamaimplementation.OllamaChatEndpoint
+	ollamaimplementation.OllamaChatEndpoint = llmServer.URL
+	defer func() { ollamaimplementation.OllamaChatEndpoint = originalEndpoint }()
+
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", MaxIterations: 2, SystemPrompt: "Answer in Go only."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Lang = versionLang{job.Lang}
+
+	RunCompilationJob(job, &recordingSink{})
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	roles := func(messages []ollamaimplementation.Message) string {
+		var out []string
+		for _, m := range messages {
+			out = append(out, m.Role)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := roles(requests[0]); got != "system,user" {
+		t.Errorf("first request roles = %s", got)
+	}
+	if got := roles(requests[1]); got != "system,user,assistant,user" {
+		t.Fatalf("second request roles = %s", got)
+	}
+
+	system := requests[0][0].Content
+	if !strings.HasPrefix(system, "Answer in Go only.") || !strings.Contains(system, "Generate Go code") {
+		t.Errorf("system message:\n%s", system)
+	}
+	if !strings.Contains(requests[1][2].Content, "alpha") || requests[1][1].Content != requests[0][1].Content {
+		t.Errorf("second request does not continue the first: %+v", requests[1])
+	}
+	if conversation := job.snapshot().Data.(jobStatusData).Conversation; roles(conversation) != "system,user,assistant,user,assistant" {
+		t.Errorf("job conversation roles = %s", roles(conversation))
+	}
+}
+
+func TestRunCompilationJobOffline(t *testing.T) {
+	const (
+		broken = "```go\npackage main\n\nfunc Add(a, b int) int {\n\treturn a + c\n}\n\nfunc main() {}\n```\n"
+		fixed  = "```go\npackage main\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc main() {}\n```\n"
+		tests  = "```go\npackage main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}\n```\n"
+	)
+	script, _ := json.Marshal([]string{broken + "\n" + tests, fixed + "\n" + tests})
+	useConfig(t, `{"providers": {"canned": {"type": "scripted", "responses": `+string(script)+`}}}`)
+
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Provider: "canned", MaxIterations: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &recordingSink{}
+
+	RunCompilationJob(job, newJobSink(job, sink))
+
+	completions := sink.ofType(WSTypeCompletion)
+	if len(completions) != 1 {
+		t.Fatalf("got %d completion messages, want 1; status %s (%s)", len(completions), job.Status, job.AbortReason)
+	}
+
+	// The responses were streamed line by line, and each iteration went
+	// through its phases in order
+	var streamed strings.Builder
+	for _, msg := range sink.ofType(WSTypeToken) {
+		if data := msg.Data.(WSTokenData); data.Iteration == 1 {
+			streamed.WriteString(data.Text)
+		}
+	}
+	if streamed.String() != broken+"\n"+tests {
+		t.Errorf("streamed response:\n%s", streamed.String())
+	}
+	phases := map[int][]string{}
+	for _, msg := range sink.ofType(WSTypePhase) {
+		data := msg.Data.(WSPhaseData)
+		phases[data.Iteration] = append(phases[data.Iteration], data.Phase)
+	}
+	if got := strings.Join(phases[1], ","); got != "generating,extracting,compiling" {
+		t.Errorf("iteration 1 phases = %s", got)
+	}
+	if got := strings.Join(phases[2], ","); got != "generating,extracting,compiling,testing" {
+		t.Errorf("iteration 2 phases = %s", got)
+	}
+	for _, msg := range job.snapshot().Data.(jobStatusData).Messages {
+		if msg.Type == WSTypeToken {
+			t.Fatal("tokens recorded on the job")
+		}
+	}
+	if data := completions[0].Data.(WSCompletionData); data.TotalIterations != 2 || !strings.Contains(data.Code, "a + b") {
+		t.Errorf("completion = %+v", data)
+	}
+
+	requests := job.LLM.(*llm.Scripted).Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	repair := requests[1].Messages[len(requests[1].Messages)-1].Content
+	if !strings.Contains(repair, "undefined: c") {
+		t.Errorf("repair prompt does not report the compile error:\n%s", repair)
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"fmt"
+	"llama/modules/language"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// REPAIR PROMPTS
+// ============================================================================
+
+const (
+	maxPromptFrames      = 8
+	maxPromptOutputLines = 30
+	maxHistoryAttempts   = 5 // Earlier attempts summarized in a compacted prompt
+	contextRadius        = 2 // Source lines shown either side of an error
+)
+
+// promptDetail controls how much of a failed attempt goes into a repair
+// prompt. Less detail is used when the prompt would not fit the model's
+// token budget otherwise.
+type promptDetail struct {
+	name           string
+	showMainCode   bool
+	showTestCode   bool
+	maxDiagnostics int // Later errors are usually follow-on errors of the first few
+	maxFailedTests int
+}
+
+var (
+	detailFull    = promptDetail{name: "full", showMainCode: true, showTestCode: true, maxDiagnostics: 10, maxFailedTests: 5}
+	detailCompact = promptDetail{name: "compact", showMainCode: true, maxDiagnostics: 5, maxFailedTests: 3}
+	detailMinimal = promptDetail{name: "minimal", maxDiagnostics: 3, maxFailedTests: 2}
+)
+
+// subtypeHints are targeted repair instructions for common error subtypes
+var subtypeHints = map[string]string{
+	"syntax":            "The code does not parse. Check for unbalanced braces, missing commas and statements outside functions.",
+	"unused_import":     "Remove every import that is not used, or use it.",
+	"unused_variable":   "Remove variables that are declared but never used, or assign them to _.",
+	"undefined":         "A name is used but never declared. Define it, fix its spelling, or add the missing import.",
+	"missing_return":    "Every code path of a function with results must end in a return statement.",
+	"type_mismatch":     "Values are used with the wrong type. Add explicit conversions or change the declared types.",
+	"argument_count":    "A function is called with the wrong number of arguments or return values. Match its signature.",
+	"redeclared":        "A name is declared twice. Keep one declaration; the test file must not redefine functions from the main file.",
+	"unknown_package":   "An import path does not exist. Use only the standard library.",
+	"vet":               "go vet rejected the code. Fix format strings and other suspicious constructs it reports.",
+	"assertion_failure": "The code builds but returns wrong results. Fix the logic, not the tests, unless the test expectation is clearly wrong.",
+	"panic":             "The code panics at run time. Guard against nil values, out-of-range indexes and division by zero.",
+	"timeout":           "The tests did not finish in time. Look for infinite loops and blocking channel operations.",
+	"deadlock":          "All goroutines are blocked. Make sure every channel send has a receiver and every lock is released.",
+}
+
+// buildRepairPrompt explains a failed attempt to the LLM: the code it wrote,
+// what went wrong in a form suited to the error type, and what to do next.
+// With patch set it asks for a unified diff instead of the full code.
+func buildRepairPrompt(lang language.Language, attempt *Attempt, detail promptDetail, patch bool) string {
+	result := attempt.Result
+	mainFile, testFile := lang.FileNames()
+	sources := map[string]string{mainFile: attempt.MainCode, testFile: attempt.TestCode}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n\n=== ERROR FEEDBACK FROM ITERATION %d ===\n", attempt.Iteration))
+	b.WriteString(fmt.Sprintf("Error Type: %s\n", result.ErrorType))
+	if result.Stage != "" {
+		b.WriteString(fmt.Sprintf("Failed Stage: %s\n", result.Stage))
+	}
+	if hint, ok := subtypeHints[result.ErrorSubtype]; ok {
+		b.WriteString("Hint: " + hint + "\n")
+	}
+
+	var shown []string
+	if detail.showMainCode {
+		shown = append(shown, mainFile)
+	}
+	if detail.showTestCode {
+		shown = append(shown, testFile)
+	}
+	if len(shown) > 0 {
+		b.WriteString("\nYour previous code:\n")
+	}
+	for _, name := range shown {
+		if strings.TrimSpace(sources[name]) == "" {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("--- %s ---\n```%s\n%s\n```\n", name, lang.Name(), strings.TrimRight(sources[name], "\n")))
+	}
+	b.WriteString("\n")
+
+	switch result.ErrorType {
+	case ErrorTypeSyntax, ErrorTypeType:
+		b.WriteString(compileErrorSection(result, sources, detail.maxDiagnostics))
+	case ErrorTypeLogic:
+		b.WriteString(logicErrorSection(result, sources, detail.maxFailedTests))
+	case ErrorTypeRuntime:
+		b.WriteString(runtimeErrorSection(result, sources))
+	default:
+		b.WriteString(outputSection(result))
+	}
+
+	if patch {
+		b.WriteString(patchInstructions(mainFile, testFile))
+	} else {
+		b.WriteString("\nFix the problems above and output the complete corrected code in the same two-block format.\n")
+	}
+	return b.String()
+}
+
+// attemptHistory summarizes the attempts before the last one, a line each.
+// It stands in for the conversation context once that has been dropped, so
+// the LLM still knows what it already tried.
+func attemptHistory(attempts []Attempt) string {
+	if len(attempts) < 2 {
+		return ""
+	}
+	earlier := attempts[:len(attempts)-1]
+
+	var b strings.Builder
+	b.WriteString("\n\n=== EARLIER ATTEMPTS ===\n")
+	if len(earlier) > maxHistoryAttempts {
+		b.WriteString(fmt.Sprintf("(%d older attempts omitted)\n", len(earlier)-maxHistoryAttempts))
+		earlier = earlier[len(earlier)-maxHistoryAttempts:]
+	}
+	for _, a := range earlier {
+		b.WriteString(fmt.Sprintf("- Iteration %d: %s", a.Iteration, a.Result.ErrorType))
+		if a.Result.ErrorSubtype != "" {
+			b.WriteString(fmt.Sprintf(" (%s)", a.Result.ErrorSubtype))
+		}
+		if msgs := failureMessages(a.Result); len(msgs) > 0 {
+			first := strings.SplitN(strings.TrimSpace(sanitizeOutput(msgs[0])), "\n", 2)[0]
+			b.WriteString(fmt.Sprintf(", %d error(s), first: %s", len(msgs), truncate(first, 120)))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Do not repeat these mistakes.\n")
+	return b.String()
+}
+
+// compileErrorSection lists each error with numbered source context
+func compileErrorSection(result *CompilationResult, sources map[string]string, limit int) string {
+	diags := result.Diagnostics
+	if len(diags) == 0 {
+		diags = locateErrors(append(append([]string{}, result.CompileErrors...), result.TestErrors...))
+	}
+
+	var b strings.Builder
+	b.WriteString("Compile errors:\n")
+	for i, d := range diags {
+		if i == limit {
+			b.WriteString(fmt.Sprintf("... and %d more\n", len(diags)-limit))
+			break
+		}
+
+		if loc := d.Location(); loc != "" {
+			b.WriteString(loc + ": ")
+		}
+		b.WriteString(sanitizeOutput(d.Message) + "\n")
+		b.WriteString(sourceContext(sources[d.File], d.Line, d.Column))
+	}
+	return b.String()
+}
+
+// logicErrorSection names the failing tests with what they got and wanted
+func logicErrorSection(result *CompilationResult, sources map[string]string, limit int) string {
+	var b strings.Builder
+
+	// Messages per failing test, from diagnostics or the backend's error list
+	messages := make(map[string][]Diagnostic)
+	var order []string
+	diags := result.Diagnostics
+	if len(diags) == 0 {
+		diags = locateErrors(result.TestErrors)
+	}
+	for _, d := range diags {
+		if _, ok := messages[d.Test]; !ok {
+			order = append(order, d.Test)
+		}
+		messages[d.Test] = append(messages[d.Test], d)
+	}
+
+	if report := result.Tests; report != nil {
+		b.WriteString(fmt.Sprintf("Tests: %d of %d passed.\n", report.Passed, report.Passed+report.Failed))
+		order = report.FailedTests()
+	}
+
+	b.WriteString("Failing tests:\n")
+	for i, name := range order {
+		if i == limit {
+			b.WriteString(fmt.Sprintf("... and %d more\n", len(order)-limit))
+			break
+		}
+
+		if name == "" {
+			name = "(unknown test)"
+		}
+		b.WriteString("- " + name + "\n")
+		for _, d := range messages[name] {
+			msg := sanitizeOutput(d.Message)
+			if got, want, ok := gotWant(msg); ok {
+				b.WriteString(fmt.Sprintf("    got:  %s\n    want: %s\n", got, want))
+			} else {
+				b.WriteString("    " + strings.ReplaceAll(msg, "\n", "\n    ") + "\n")
+			}
+			if loc := d.Location(); loc != "" {
+				b.WriteString("    at " + loc + "\n")
+				b.WriteString(indent(sourceContext(sources[d.File], d.Line, 0), "    "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// runtimeErrorSection shows the crash and the stack frames in generated code
+func runtimeErrorSection(result *CompilationResult, sources map[string]string) string {
+	var b strings.Builder
+	b.WriteString("Runtime failure:\n")
+
+	var crash []string
+	for _, d := range result.Diagnostics {
+		if strings.HasPrefix(d.Message, "panic: ") || strings.HasPrefix(d.Message, "fatal error: ") {
+			crash = append(crash, d.Message)
+		}
+	}
+	if len(crash) == 0 {
+		crash = append(crash, result.TestErrors...)
+	}
+	if len(crash) == 0 {
+		crash = append(crash, result.CompileErrors...)
+	}
+	for _, line := range crash {
+		if line = sanitizeOutput(line); line != "" {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	frames := stackFrames(result.Output, sources)
+	if len(frames) > 0 {
+		b.WriteString("Stack trace (generated code only, innermost first):\n")
+		for i, f := range frames {
+			if i == maxPromptFrames {
+				break
+			}
+			b.WriteString(fmt.Sprintf("  %s (%s:%d)", f.function, f.file, f.line))
+			if src := sourceLine(sources[f.file], f.line); src != "" {
+				b.WriteString(": " + src)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// outputSection is the fallback: the tail of the cleaned up output
+func outputSection(result *CompilationResult) string {
+	lines := strings.Split(strings.TrimSpace(sanitizeOutput(result.Output)), "\n")
+	if len(lines) > maxPromptOutputLines {
+		lines = lines[len(lines)-maxPromptOutputLines:]
+	}
+	return "Output:\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// ============================================================================
+// SOURCE CONTEXT
+// ============================================================================
+
+// sourceContext renders the lines around line (1-based) with numbers, the
+// offending line marked with '>' and, if col is known, a caret under it
+func sourceContext(src string, line, col int) string {
+	if src == "" || line <= 0 {
+		return ""
+	}
+	lines := strings.Split(src, "\n")
+	if line > len(lines) {
+		return ""
+	}
+
+	first, last := line-contextRadius, line+contextRadius
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		b.WriteString(fmt.Sprintf("%s %*d | %s\n", marker, width, n, lines[n-1]))
+
+		if n == line && col > 0 && col <= len(lines[n-1])+1 {
+			// Keep tabs so the caret lines up with the code above it
+			pad := []byte(lines[n-1][:col-1])
+			for i, c := range pad {
+				if c != '\t' {
+					pad[i] = ' '
+				}
+			}
+			b.WriteString(fmt.Sprintf("  %*s | %s^\n", width, "", pad))
+		}
+	}
+	return b.String()
+}
+
+func sourceLine(src string, line int) string {
+	lines := strings.Split(src, "\n")
+	if line <= 0 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
+
+func indent(s, prefix string) string {
+	if s == "" {
+		return ""
+	}
+	return prefix + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n"+prefix) + "\n"
+}
+
+// locatedErrorPattern finds "file.ext:line[:col]" in a backend's error line
+var locatedErrorPattern = regexp.MustCompile(`(?:^|\s)(?:(\S+?): )?([\w.-]+\.\w+):(\d+)(?::(\d+))?:?\s*(.*)$`)
+
+// locateErrors turns plain error strings into diagnostics for backends that
+// don't report structured ones. Lines without a location keep only the message.
+func locateErrors(errors []string) []Diagnostic {
+	var diags []Diagnostic
+	for _, e := range errors {
+		m := locatedErrorPattern.FindStringSubmatch(e)
+		if m == nil {
+			diags = append(diags, Diagnostic{Message: e})
+			continue
+		}
+		line, _ := strconv.Atoi(m[3])
+		col, _ := strconv.Atoi(m[4])
+		diags = append(diags, Diagnostic{Test: m[1], File: m[2], Line: line, Column: col, Message: m[5]})
+	}
+	return diags
+}
+
+// gotWantPatterns pull the actual and expected values out of a test message
+var gotWantPatterns = []struct {
+	re            *regexp.Regexp
+	gotIx, wantIx int
+}{
+	{regexp.MustCompile(`(?is)\bgot:?\s*(.+?)[,;]?\s+(?:want|expected):?\s*(.+)`), 1, 2},
+	{regexp.MustCompile(`(?is)\b(?:want|expected):?\s*(.+?)[,;]?\s+(?:got|but got|actual):?\s*(.+)`), 2, 1},
+	{regexp.MustCompile(`(?s)AssertionError:\s*(.+?)\s*!=\s*(.+)`), 1, 2},
+}
+
+func gotWant(msg string) (got, want string, ok bool) {
+	for _, p := range gotWantPatterns {
+		if m := p.re.FindStringSubmatch(msg); m != nil {
+			return strings.TrimSpace(m[p.gotIx]), strings.TrimSpace(m[p.wantIx]), true
+		}
+	}
+	return "", "", false
+}
+
+// ============================================================================
+// STACK TRACES
+// ============================================================================
+
+type stackFrame struct {
+	function string
+	file     string
+	line     int
+}
+
+var (
+	// Go: "main.Divide(...)" followed by "\t/tmp/go_compile_1/main.go:6 +0x1d"
+	goFrameLocation = regexp.MustCompile(`^\t(\S+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	// Python: File "/tmp/python_compile_1/main.py", line 5, in divide
+	pyFrame = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+), in (\S+)`)
+)
+
+// stackFrames extracts the frames that point into the generated sources,
+// dropping the runtime, the test framework and duplicate goroutine dumps
+func stackFrames(output string, sources map[string]string) []stackFrame {
+	var frames []stackFrame
+	seen := make(map[string]bool)
+	lines := strings.Split(output, "\n")
+
+	add := func(function, path string, line int) {
+		file := filepath.Base(path)
+		if _, ok := sources[file]; !ok {
+			return
+		}
+		key := fmt.Sprintf("%s:%d", file, line)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		frames = append(frames, stackFrame{function: function, file: file, line: line})
+	}
+
+	var pyFrames []stackFrame
+	for i, line := range lines {
+		if m := goFrameLocation.FindStringSubmatch(line); m != nil && i > 0 {
+			n, _ := strconv.Atoi(m[2])
+			add(goFunctionName(lines[i-1]), m[1], n)
+			continue
+		}
+		if m := pyFrame.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			pyFrames = append(pyFrames, stackFrame{function: m[3], file: m[1], line: n})
+		}
+	}
+
+	// Python prints the innermost frame last
+	for i := len(pyFrames) - 1; i >= 0; i-- {
+		add(pyFrames[i].function, pyFrames[i].file, pyFrames[i].line)
+	}
+	return frames
+}
+
+// goFunctionName turns "temp_module.(*Stack).Pop(0xc0000a)" into "(*Stack).Pop"
+func goFunctionName(line string) string {
+	name := strings.TrimSpace(line)
+	if paren := strings.LastIndex(name, "("); paren > 0 && strings.HasSuffix(name, ")") {
+		name = name[:paren]
+	}
+	if dot := strings.Index(name, "."); dot >= 0 && !strings.HasPrefix(name, "(") {
+		name = name[dot+1:]
+	}
+	return name
+}
+
+// ============================================================================
+// OUTPUT SANITIZING
+// ============================================================================
+
+var (
+	// Workspace directories of the compiler backends, e.g. /tmp/go_compile_123/
+	tempPathPattern = regexp.MustCompile(`(?:[A-Za-z]:)?[^\s"':]*[/\\](?:go|python|cpp|rust)_compile_\d+[/\\]`)
+
+	// Lines that carry no information about the generated code
+	noisePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^go: (creating new go\.mod|to add module requirements|finding module|downloading)`),
+		regexp.MustCompile(`^\s*go mod tidy$`),
+		regexp.MustCompile(`^exit status \d+$`),
+		regexp.MustCompile(`^(FAIL|ok)\s+temp_module`),
+		regexp.MustCompile(`^(FAIL|PASS)$`),
+		regexp.MustCompile(`^# \[?temp_module`),
+		regexp.MustCompile(`^\$ go `),
+		regexp.MustCompile(`^goroutine \d+ \[`),
+		regexp.MustCompile(`^\t\S*/(src|libexec/src)/(runtime|testing|reflect)/`),
+		regexp.MustCompile(`^(testing|runtime|reflect)\.`),
+		regexp.MustCompile(`^created by (testing|runtime)\.`),
+		regexp.MustCompile(`^\s*File "[^"]*/lib/python[\d.]*/`),
+	}
+)
+
+// sanitizeOutput strips workspace paths and toolchain chatter from output
+// before it goes into a prompt
+func sanitizeOutput(s string) string {
+	s = tempPathPattern.ReplaceAllString(s, "")
+
+	var kept []string
+	for _, line := range strings.Split(s, "\n") {
+		noise := false
+		for _, p := range noisePatterns {
+			if p.MatchString(line) {
+				noise = true
+				break
+			}
+		}
+		if !noise {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
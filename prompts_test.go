@@ -0,0 +1,199 @@
+package main
+
+import (
+	"llama/modules/language"
+	"llama/modules/language/golang"
+	"llama/modules/language/python"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeOutput(t *testing.T) {
+	in := `go: creating new go.mod: module temp_module
+go: to add module requirements and sums:
+	go mod tidy
+# temp_module [temp_module.test]
+/tmp/go_compile_1712/main.go:6:13: undefined: c
+goroutine 8 [running]:
+testing.tRunner(0x25121ba06488, 0x6d44d0)
+	/usr/local/go/src/testing/testing.go:2193 +0xea
+  File "/tmp/python_compile_99/main.py", line 3, in divide
+exit status 1
+FAIL	temp_module	0.005s
+FAIL`
+
+	got := sanitizeOutput(in)
+	want := "main.go:6:13: undefined: c\n  File \"main.py\", line 3, in divide"
+	if got != want {
+		t.Errorf("sanitizeOutput =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSourceContext(t *testing.T) {
+	src := "package main\n\nfunc Add(a, b int) int {\n\treturn a + c\n}\n\nfunc main() {}"
+
+	got := sourceContext(src, 4, 13)
+	want := "  2 | \n" +
+		"  3 | func Add(a, b int) int {\n" +
+		"> 4 | \treturn a + c\n" +
+		"    | \t           ^\n" +
+		"  5 | }\n" +
+		"  6 | \n"
+	if got != want {
+		t.Errorf("sourceContext =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := sourceContext(src, 1, 0); !strings.HasPrefix(got, "> 1 | package main\n  2 | \n  3 |") {
+		t.Errorf("context at first line =\n%s", got)
+	}
+	if got := sourceContext(src, 99, 0); got != "" {
+		t.Errorf("out of range line gave %q", got)
+	}
+}
+
+func TestGotWant(t *testing.T) {
+	tests := []struct {
+		msg, got, want string
+		ok             bool
+	}{
+		{"Add(1, 2) = got 4, want 3", "4", "3", true},
+		{"expected 10, got 7", "7", "10", true},
+		{"AssertionError: 5 != 6", "5", "6", true},
+		{"something went wrong", "", "", false},
+	}
+	for _, test := range tests {
+		got, want, ok := gotWant(test.msg)
+		if got != test.got || want != test.want || ok != test.ok {
+			t.Errorf("gotWant(%q) = %q, %q, %v", test.msg, got, want, ok)
+		}
+	}
+}
+
+func TestLocateErrors(t *testing.T) {
+	diags := locateErrors([]string{
+		"main.cpp:3:5: error: expected ';' before '}' token",
+		"test_add: main.py:5: ZeroDivisionError: division by zero",
+		"Compilation exceeded timeout",
+	})
+
+	want := []Diagnostic{
+		{File: "main.cpp", Line: 3, Column: 5, Message: "error: expected ';' before '}' token"},
+		{Test: "test_add", File: "main.py", Line: 5, Message: "ZeroDivisionError: division by zero"},
+		{Message: "Compilation exceeded timeout"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics: %+v", len(diags), diags)
+	}
+	for i := range want {
+		if diags[i] != want[i] {
+			t.Errorf("diagnostic %d = %+v, want %+v", i, diags[i], want[i])
+		}
+	}
+}
+
+func TestStackFrames(t *testing.T) {
+	goOutput := `panic: runtime error: integer divide by zero [recovered]
+
+goroutine 7 [running]:
+testing.tRunner.func1.2({0x5b7e60, 0x6a3d30})
+	/usr/local/go/src/testing/testing.go:1631 +0x24a
+temp_module.Divide(...)
+	/tmp/go_compile_5/main.go:4
+temp_module.TestDivide(0xc000007860?)
+	/tmp/go_compile_5/main_test.go:6 +0x1d
+testing.tRunner(0xc000007860, 0x5d8c18)
+	/usr/local/go/src/testing/testing.go:1689 +0xfb`
+
+	frames := stackFrames(goOutput, map[string]string{"main.go": "", "main_test.go": ""})
+	want := []stackFrame{{"Divide", "main.go", 4}, {"TestDivide", "main_test.go", 6}}
+	if len(frames) != len(want) || frames[0] != want[0] || frames[1] != want[1] {
+		t.Errorf("go frames = %+v, want %+v", frames, want)
+	}
+
+	pyOutput := `Traceback (most recent call last):
+  File "/tmp/python_compile_1/test_main.py", line 7, in test_divide
+    self.assertEqual(divide(1, 0), 0)
+  File "/tmp/python_compile_1/main.py", line 2, in divide
+    return a / b
+ZeroDivisionError: division by zero`
+
+	frames = stackFrames(pyOutput, map[string]string{"main.py": "", "test_main.py": ""})
+	want = []stackFrame{{"divide", "main.py", 2}, {"test_divide", "test_main.py", 7}}
+	if len(frames) != len(want) || frames[0] != want[0] || frames[1] != want[1] {
+		t.Errorf("python frames = %+v, want %+v", frames, want)
+	}
+}
+
+func TestRepairPromptLogic(t *testing.T) {
+	attempt := &Attempt{
+		Iteration: 2,
+		MainCode:  "package main\n\nfunc Double(n int) int { return n + 2 }\n",
+		TestCode:  "package main\n\nimport \"testing\"\n\nfunc TestDouble(t *testing.T) {\n\tif got := Double(5); got != 10 {\n\t\tt.Errorf(\"got %d, want 10\", got)\n\t}\n}\n",
+		Result: &CompilationResult{
+			ErrorType: ErrorTypeLogic,
+			Diagnostics: []Diagnostic{
+				{File: "main_test.go", Line: 7, Message: "got 7, want 10", Phase: "test", Test: "TestDouble"},
+			},
+			Tests: &TestReport{
+				Tests:  []language.TestCase{{Name: "TestDouble", Status: "fail"}, {Name: "TestOther", Status: "pass"}},
+				Passed: 1,
+				Failed: 1,
+			},
+		},
+	}
+
+	prompt := buildRepairPrompt(golang.New(), attempt, detailFull, false)
+	for _, want := range []string{
+		"Tests: 1 of 2 passed.",
+		"- TestDouble\n    got:  7\n    want: 10\n    at main_test.go:7\n",
+		"    > 7 | \t\tt.Errorf(",
+		"--- main_test.go ---",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestRepairPromptRuntime(t *testing.T) {
+	attempt := &Attempt{
+		Iteration: 1,
+		MainCode:  "def divide(a, b):\n    return a / b\n",
+		TestCode:  "import unittest\nfrom main import divide\n",
+		Result: &CompilationResult{
+			ErrorType:  ErrorTypeRuntime,
+			TestErrors: []string{"test_divide: main.py:2: ZeroDivisionError: division by zero"},
+			Output:     "  File \"/tmp/python_compile_1/main.py\", line 2, in divide\n    return a / b\nZeroDivisionError: division by zero\n",
+		},
+	}
+
+	prompt := buildRepairPrompt(python.New(), attempt, detailFull, false)
+	for _, want := range []string{
+		"Runtime failure:\n  test_divide: main.py:2: ZeroDivisionError: division by zero\n",
+		"  divide (main.py:2): return a / b\n",
+		"```python\ndef divide(a, b):",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "python_compile_1") {
+		t.Errorf("prompt leaks the temp path:\n%s", prompt)
+	}
+}
+
+func TestRepairPromptFallsBackToOutput(t *testing.T) {
+	attempt := &Attempt{
+		Iteration: 1,
+		MainCode:  "package main\n",
+		Result: &CompilationResult{
+			ErrorType: ErrorTypeInfrastructure,
+			Output:    "go: creating new go.mod: module temp_module\nsomething broke in /tmp/go_compile_9/go.mod\n",
+		},
+	}
+
+	prompt := buildRepairPrompt(golang.New(), attempt, detailFull, false)
+	if !strings.Contains(prompt, "Output:\nsomething broke in go.mod\n") {
+		t.Errorf("unexpected fallback section:\n%s", prompt)
+	}
+}
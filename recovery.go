@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"llama/modules/language"
+)
+
+// ============================================================================
+// INFRASTRUCTURE RECOVERY
+// ============================================================================
+
+// stdlibOnlyInstructions is added to prompts once third-party imports
+// turned out not to be resolvable
+const stdlibOnlyInstructions = `
+- Use ONLY the standard library; third-party packages are not available
+`
+
+// recoverInfrastructure works through the backend's recovery actions for a
+// result that failed with an infrastructure error, reporting each one to
+// the client. It stops at the first action that gets past the error and
+// returns the latest result. No LLM iterations are spent.
+func recoverInfrastructure(job *ExecutionJob, sink MessageSink, iteration int, mainCode, testCode string, result *CompilationResult) *CompilationResult {
+	recoverer, ok := job.Lang.(language.Recoverer)
+	if !ok {
+		return result
+	}
+
+	for _, action := range recoverer.RecoveryActions(result) {
+		if job.Ctx.Err() != nil {
+			break
+		}
+
+		fmt.Printf("[Job %s] Infrastructure recovery: %s\n", job.ID, action)
+
+		ctx, cancel := context.WithTimeout(job.Ctx, compileTimeout(job))
+		retry, err := recoverer.Recover(ctx, action, mainCode, testCode)
+		cancel()
+
+		if err != nil {
+			sendRecoveryMessage(sink, iteration, action, nil, err.Error())
+			continue
+		}
+
+		classifyResult(job, retry)
+		result = retry
+		if result.ErrorType != ErrorTypeInfrastructure {
+			sendRecoveryMessage(sink, iteration, action, result, "Recovered")
+			break
+		}
+		sendRecoveryMessage(sink, iteration, action, result, firstError(result))
+	}
+
+	return result
+}
+
+// needsStdlibOnly reports whether the failure was an import that could not
+// be resolved, which the LLM can fix by not using third-party packages
+func needsStdlibOnly(job *ExecutionJob, result *CompilationResult) bool {
+	return !job.StdlibOnly && result.ErrorSubtype == "missing_module"
+}
+
+// firstError returns the first error message of a result
+func firstError(result *CompilationResult) string {
+	if msgs := failureMessages(result); len(msgs) > 0 {
+		return msgs[0]
+	}
+	return "Infrastructure error"
+}
+
+func sendRecoveryMessage(sink MessageSink, iteration int, action string, result *CompilationResult, message string) {
+	data := WSRecoveryData{
+		Iteration: iteration,
+		Action:    action,
+		ErrorType: ErrorTypeInfrastructure.String(),
+		Message:   message,
+	}
+	if result != nil {
+		data.Recovered = result.ErrorType != ErrorTypeInfrastructure
+		data.ErrorType = result.ErrorType.String()
+	}
+
+	msg := WSMessage{
+		Type: WSTypeRecovery,
+		Data: data,
+	}
+
+	sink.Send(msg)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"llama/modules/language"
+	"llama/modules/language/golang"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingSink keeps the messages a job sends
+type recordingSink struct {
+	mu       sync.Mutex
+	messages []WSMessage
+}
+
+func (s *recordingSink) Send(msg WSMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// ofType returns the messages of type t
+func (s *recordingSink) ofType(t WSMessageType) []WSMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []WSMessage
+	for _, msg := range s.messages {
+		if msg.Type == t {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// recoveringLang is a backend whose recovery action fixAt gets past the
+// infrastructure error, and whose action "b" can't be performed at all
+type recoveringLang struct {
+	language.Language
+	fixAt string
+	tried []string
+}
+
+func (l *recoveringLang) RecoveryActions(result *CompilationResult) []string {
+	return []string{"a", "b", "c"}
+}
+
+func (l *recoveringLang) Recover(ctx context.Context, action, mainCode, testCode string) (*CompilationResult, error) {
+	l.tried = append(l.tried, action)
+	if action == "b" {
+		return nil, errors.New("b is broken")
+	}
+	if action == l.fixAt {
+		return &CompilationResult{Success: true, ErrorType: ErrorTypeSuccess}, nil
+	}
+	return &CompilationResult{ErrorType: ErrorTypeInfrastructure, CompileErrors: []string{"still broken"}}, nil
+}
+
+func recoveryJob(t *testing.T, lang language.Language) *ExecutionJob {
+	t.Helper()
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(job.Cancel)
+	job.Lang = lang
+	return job
+}
+
+func TestRecoverInfrastructure(t *testing.T) {
+	lang := &recoveringLang{Language: golang.New(), fixAt: "c"}
+	job := recoveryJob(t, lang)
+	sink := &recordingSink{}
+
+	failed := &CompilationResult{ErrorType: ErrorTypeInfrastructure}
+	result := recoverInfrastructure(job, sink, 1, "package main", "", failed)
+
+	if !result.Success {
+		t.Errorf("expected the last action to recover, got %+v", result)
+	}
+	if strings.Join(lang.tried, ",") != "a,b,c" {
+		t.Errorf("tried %v", lang.tried)
+	}
+
+	steps := sink.ofType(WSTypeRecovery)
+	if len(steps) != 3 {
+		t.Fatalf("got %d recovery messages, want one per action", len(steps))
+	}
+	want := []WSRecoveryData{
+		{Iteration: 1, Action: "a", ErrorType: "infrastructure", Message: "still broken"},
+		{Iteration: 1, Action: "b", ErrorType: "infrastructure", Message: "b is broken"},
+		{Iteration: 1, Action: "c", Recovered: true, ErrorType: "success", Message: "Recovered"},
+	}
+	for i, msg := range steps {
+		if got := msg.Data.(WSRecoveryData); got != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestRecoverInfrastructureStopsAtFirstFix(t *testing.T) {
+	lang := &recoveringLang{Language: golang.New(), fixAt: "a"}
+	job := recoveryJob(t, lang)
+
+	recoverInfrastructure(job, &recordingSink{}, 1, "package main", "", &CompilationResult{ErrorType: ErrorTypeInfrastructure})
+	if len(lang.tried) != 1 {
+		t.Errorf("tried %v after the first action recovered", lang.tried)
+	}
+}
+
+func TestStdlibOnlyPrompt(t *testing.T) {
+	job := recoveryJob(t, golang.New())
+	result := &CompilationResult{ErrorType: ErrorTypeInfrastructure, ErrorSubtype: "missing_module"}
+
+	if !needsStdlibOnly(job, result) {
+		t.Error("an unresolvable import should switch to stdlib-only mode")
+	}
+	job.StdlibOnly = true
+	if needsStdlibOnly(job, result) {
+		t.Error("stdlib-only mode should only be entered once")
+	}
+
+	if prompt, _ := buildPrompt(job, 1); !strings.Contains(prompt, "ONLY the standard library") {
+		t.Errorf("prompt missing the stdlib-only rule:\n%s", prompt)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"llama/modules/language"
+	"llama/modules/llm"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// RECORD AND REPLAY
+// ============================================================================
+
+// bundleVersion is bumped when a Bundle changes incompatibly
+const bundleVersion = 1
+
+// toolchainTimeout bounds asking a backend for its toolchain version
+const toolchainTimeout = 10 * time.Second
+
+// replayDir is where bundles are saved, one <job ID>.json per recorded job
+var replayDir = "replays"
+
+// Bundle is everything needed to rerun a job without a model: its settings
+// as resolved, every exchange with the LLM, and what the pipeline made of
+// each response
+type Bundle struct {
+	Version    int               `json:"version"`
+	JobID      string            `json:"jobId"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	Request    CompileRequest    `json:"request"`   // Defaults filled in, so a replay doesn't depend on the config
+	Toolchain  map[string]string `json:"toolchain"` // Version by tool, e.g. "go" and "server"
+	Exchanges  []llm.Exchange    `json:"exchanges"`
+	Candidates []BundleCandidate `json:"candidates"`
+	Outcome    BundleOutcome     `json:"outcome"`
+}
+
+// BundleCandidate is what the pipeline made of one candidate's response
+type BundleCandidate struct {
+	Iteration    int       `json:"iteration"`
+	Candidate    int       `json:"candidate"`
+	Extraction   string    `json:"extraction,omitempty"`
+	MainCode     string    `json:"mainCode,omitempty"` // After auto-fixes
+	TestCode     string    `json:"testCode,omitempty"`
+	AutoFixes    []AutoFix `json:"autoFixes,omitempty"`
+	Patch        string    `json:"patch,omitempty"`
+	PatchError   string    `json:"patchError,omitempty"`
+	ErrorType    string    `json:"errorType,omitempty"` // Before any infrastructure recovery
+	ErrorSubtype string    `json:"errorSubtype,omitempty"`
+	AbortCode    string    `json:"abortCode,omitempty"`
+}
+
+// BundleOutcome is how the job ended
+type BundleOutcome struct {
+	Status      string `json:"status"`
+	AbortReason string `json:"abortReason,omitempty"`
+	Iterations  int    `json:"iterations"`
+}
+
+// recordCandidates adds an iteration's candidates to the job's recording
+func (job *ExecutionJob) recordCandidates(candidates []*candidate) {
+	if job.Recorder == nil {
+		return
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	for _, c := range candidates {
+		recorded := BundleCandidate{
+			Iteration:  c.Iteration,
+			Candidate:  c.Index,
+			Extraction: c.Extraction,
+			MainCode:   c.MainCode,
+			TestCode:   c.TestCode,
+			AutoFixes:  c.AutoFixes,
+			Patch:      c.Patch,
+			PatchError: c.PatchError,
+			AbortCode:  c.AbortCode,
+		}
+		if c.Result != nil {
+			recorded.ErrorType = c.Result.ErrorType.String()
+			recorded.ErrorSubtype = c.Result.ErrorSubtype
+		}
+		job.recorded = append(job.recorded, recorded)
+	}
+}
+
+func (job *ExecutionJob) setReplayDiffs(diffs []string) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.replayDiffs = diffs
+}
+
+// bundle returns the job's recording so far
+func (job *ExecutionJob) bundle() *Bundle {
+	toolchain := map[string]string{"server": runtime.Version()}
+	if versioner, ok := job.Lang.(language.Versioner); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), toolchainTimeout)
+		if version, err := versioner.ToolchainVersion(ctx); err == nil {
+			toolchain[job.Language] = version
+		}
+		cancel()
+	}
+
+	// A replay keeps the provider of the job it replays
+	provider := job.Provider
+	if job.Replay != nil {
+		provider = job.Replay.Request.Provider
+	}
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	return &Bundle{
+		Version:   bundleVersion,
+		JobID:     job.ID,
+		CreatedAt: time.Now().UTC(),
+		Request: CompileRequest{
+			Language:      job.Language,
+			Prompt:        job.UserPrompt,
+			Model:         job.Model,
+			MaxIterations: job.MaxIterations,
+			Candidates:    job.Candidates,
+			PatchMode:     job.PatchMode,
+			Options:       job.Options,
+			SystemPrompt:  job.SystemPrompt,
+			Provider:      provider,
+			Timeout:       int(job.Timeout / time.Second),
+		},
+		Toolchain:  toolchain,
+		Exchanges:  job.Recorder.Exchanges(),
+		Candidates: append([]BundleCandidate(nil), job.recorded...),
+		Outcome: BundleOutcome{
+			Status:      job.Status,
+			AbortReason: job.AbortReason,
+			Iterations:  job.Metrics.IterationCount,
+		},
+	}
+}
+
+// finishRecording compares a replayed job with its recording, saves the
+// job's bundle if it asked to be recorded and tells sink how it went.
+// Called once the job has ended.
+func finishRecording(job *ExecutionJob, sink MessageSink) {
+	bundle := job.bundle()
+	data := WSRecordingData{JobID: job.ID}
+
+	if job.Replay != nil {
+		diffs := diffBundles(job.Replay, bundle)
+		if replay, ok := llm.Base(job.LLM).(*llm.Replay); ok && replay.Diverged() > 0 {
+			diffs = append([]string{fmt.Sprintf("%d LLM request(s) differed from the recording", replay.Diverged())}, diffs...)
+		}
+		job.setReplayDiffs(diffs)
+		data.ReplayOf, data.Differences = job.Replay.JobID, diffs
+		fmt.Printf("[Job %s] Replay of %s finished with %d difference(s)\n", job.ID, job.Replay.JobID, len(diffs))
+		for _, diff := range diffs {
+			fmt.Printf("[Job %s]   %s\n", job.ID, diff)
+		}
+	}
+
+	if job.Record {
+		if path, err := saveBundle(bundle); err != nil {
+			fmt.Printf("[Job %s] Could not save replay bundle: %v\n", job.ID, err)
+			data.Error = err.Error()
+		} else {
+			fmt.Printf("[Job %s] Replay bundle saved to %s\n", job.ID, path)
+			data.Saved = true
+		}
+	}
+
+	sink.Send(WSMessage{Type: WSTypeRecording, Data: data})
+}
+
+// jobIDPattern matches the IDs newJobID makes, which keeps bundle paths
+// inside replayDir
+var jobIDPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+
+func bundlePath(jobID string) (string, error) {
+	if !jobIDPattern.MatchString(jobID) {
+		return "", fmt.Errorf("invalid job ID %q", jobID)
+	}
+	return filepath.Join(replayDir, jobID+".json"), nil
+}
+
+// saveBundle writes a bundle to replayDir and returns its path
+func saveBundle(bundle *Bundle) (string, error) {
+	path, err := bundlePath(bundle.JobID)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(replayDir, 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// loadBundle reads a bundle file
+func loadBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if bundle.Version != bundleVersion {
+		return nil, fmt.Errorf("%s: bundle version %d, want %d", path, bundle.Version, bundleVersion)
+	}
+	return &bundle, nil
+}
+
+// newReplayJob builds a job that reruns the recorded job jobID, answering
+// its LLM requests from the recording
+func newReplayJob(jobID string, record bool) (*ExecutionJob, error) {
+	path, err := bundlePath(jobID)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := loadBundle(path)
+	if err != nil {
+		return nil, fmt.Errorf("no recording of job %s: %w", jobID, err)
+	}
+	return replayJob(bundle, record)
+}
+
+// replayJob builds a job from a bundle's settings whose LLM is the bundle
+func replayJob(bundle *Bundle, record bool) (*ExecutionJob, error) {
+	req := bundle.Request
+	req.Provider, req.Record, req.Replay = "", false, ""
+	job, err := newExecutionJob(req)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Provider = "replay"
+	job.Record = record
+	job.Replay = bundle
+	job.Recorder = llm.NewRecorder(llm.NewReplay(bundle.Exchanges))
+	job.LLM = job.Recorder
+	return job, nil
+}
+
+// diffBundles describes how a replay's pipeline results differ from the
+// recording's. Timings and code compiled after recovery aren't compared.
+func diffBundles(recorded, replayed *Bundle) []string {
+	var diffs []string
+	differ := func(what, was, is string) {
+		if was != is {
+			diffs = append(diffs, fmt.Sprintf("%s: %q, now %q", what, was, is))
+		}
+	}
+
+	type key struct{ iteration, candidate int }
+	was := make(map[key]BundleCandidate, len(recorded.Candidates))
+	for _, c := range recorded.Candidates {
+		was[key{c.Iteration, c.Candidate}] = c
+	}
+	for _, now := range replayed.Candidates {
+		k := key{now.Iteration, now.Candidate}
+		then, ok := was[k]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("iteration %d candidate %d: not in the recording", k.iteration, k.candidate))
+			continue
+		}
+		delete(was, k)
+
+		where := fmt.Sprintf("iteration %d candidate %d", k.iteration, k.candidate)
+		differ(where+" extraction", then.Extraction, now.Extraction)
+		differ(where+" auto-fixes", autoFixNames(then.AutoFixes), autoFixNames(now.AutoFixes))
+		if then.MainCode != now.MainCode || then.TestCode != now.TestCode {
+			diffs = append(diffs, where+": extracted code changed")
+		}
+		differ(where+" patch error", then.PatchError, now.PatchError)
+		differ(where+" error type", then.ErrorType, now.ErrorType)
+		differ(where+" error subtype", then.ErrorSubtype, now.ErrorSubtype)
+		differ(where+" abort code", then.AbortCode, now.AbortCode)
+	}
+	for _, c := range recorded.Candidates {
+		if _, ok := was[key{c.Iteration, c.Candidate}]; ok {
+			diffs = append(diffs, fmt.Sprintf("iteration %d candidate %d: not reached by the replay", c.Iteration, c.Candidate))
+		}
+	}
+
+	differ("status", recorded.Outcome.Status, replayed.Outcome.Status)
+	differ("abort reason", recorded.Outcome.AbortReason, replayed.Outcome.AbortReason)
+	if recorded.Outcome.Iterations != replayed.Outcome.Iterations {
+		diffs = append(diffs, fmt.Sprintf("iterations: %d, now %d", recorded.Outcome.Iterations, replayed.Outcome.Iterations))
+	}
+	return diffs
+}
+
+func autoFixNames(fixes []AutoFix) string {
+	names := make([]string, len(fixes))
+	for i, fix := range fixes {
+		names[i] = fix.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// runReplay reruns the bundle at path to completion and prints how it
+// differed from the recording. It returns the process exit code: 0 if the
+// replay matched, 1 if it didn't, 2 if it couldn't run.
+func runReplay(path string) int {
+	bundle, err := loadBundle(path)
+	if err != nil {
+		fmt.Println("Error loading replay bundle:", err)
+		return 2
+	}
+	job, err := replayJob(bundle, false)
+	if err != nil {
+		fmt.Println("Error replaying job:", err)
+		return 2
+	}
+
+	RunCompilationJob(job, newJobSink(job, nil))
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	if len(job.replayDiffs) > 0 {
+		fmt.Printf("Replay of %s differed from the recording (%s, was %s)\n", bundle.JobID, job.Status, bundle.Outcome.Status)
+		return 1
+	}
+	fmt.Printf("Replay of %s matched the recording (%s)\n", bundle.JobID, job.Status)
+	return 0
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordJob runs a job on a scripted provider that fixes its code in the
+// second iteration, saving its bundle to a temporary replay directory
+func recordJob(t *testing.T) *ExecutionJob {
+	t.Helper()
+	const (
+		broken = "```go\npackage main\n\nfunc Add(a, b int) int {\n\treturn a + c\n}\n\nfunc main() {}\n```\n"
+		fixed  = "```go\npackage main\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc main() {}\n```\n"
+		tests  = "```go\npackage main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}\n```\n"
+	)
+	script, _ := json.Marshal([]string{broken + "\n" + tests, fixed + "\n" + tests})
+	dir, _ := json.Marshal(t.TempDir())
+	useConfig(t, `{"replayDir": `+string(dir)+`, "providers": {"canned": {"type": "scripted", "responses": `+string(script)+`}}}`)
+
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers", Provider: "canned", MaxIterations: 3, Record: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &recordingSink{}
+	RunCompilationJob(job, newJobSink(job, sink))
+	if job.Status != "completed" {
+		t.Fatalf("recorded job %s (%s)", job.Status, job.AbortReason)
+	}
+
+	recordings := sink.ofType(WSTypeRecording)
+	if len(recordings) != 1 || !recordings[0].Data.(WSRecordingData).Saved {
+		t.Fatalf("recording messages = %+v", recordings)
+	}
+	return job
+}
+
+func TestRecordJob(t *testing.T) {
+	job := recordJob(t)
+
+	bundle, err := loadBundle(filepath.Join(replayDir, job.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.JobID != job.ID || bundle.Request.Provider != "canned" || bundle.Request.Prompt != "add two numbers" || bundle.Request.SystemPrompt != systemPrompt {
+		t.Errorf("bundle settings = %+v", bundle.Request)
+	}
+	if len(bundle.Exchanges) != 2 || !strings.Contains(bundle.Exchanges[0].Response, "a + c") {
+		t.Errorf("exchanges = %+v", bundle.Exchanges)
+	}
+	if n := len(bundle.Exchanges[1].Request.Messages); n != 4 {
+		t.Errorf("second request has %d messages, want the whole conversation", n)
+	}
+	if len(bundle.Candidates) != 2 || bundle.Candidates[0].ErrorType != "type" || bundle.Candidates[1].ErrorType != "success" || bundle.Candidates[0].Extraction == "" {
+		t.Errorf("candidates = %+v", bundle.Candidates)
+	}
+	if bundle.Outcome != (BundleOutcome{Status: "completed", Iterations: 2}) {
+		t.Errorf("outcome = %+v", bundle.Outcome)
+	}
+	if bundle.Toolchain["server"] == "" || !strings.HasPrefix(bundle.Toolchain["go"], "go version") {
+		t.Errorf("toolchain = %v", bundle.Toolchain)
+	}
+}
+
+func TestReplayJob(t *testing.T) {
+	recorded := recordJob(t)
+
+	job, err := newExecutionJob(CompileRequest{Replay: recorded.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Provider != "replay" || job.UserPrompt != "add two numbers" || job.Record {
+		t.Errorf("replay job = %+v", job)
+	}
+	sink := &recordingSink{}
+	RunCompilationJob(job, newJobSink(job, sink))
+
+	recordings := sink.ofType(WSTypeRecording)
+	if len(recordings) != 1 {
+		t.Fatalf("got %d recording messages, want 1", len(recordings))
+	}
+	if data := recordings[0].Data.(WSRecordingData); data.ReplayOf != recorded.ID || len(data.Differences) != 0 || data.Saved {
+		t.Errorf("recording message = %+v", data)
+	}
+	if status := job.snapshot().Data.(jobStatusData); status.ReplayOf != recorded.ID || job.Status != "completed" {
+		t.Errorf("replay %s of %s", job.Status, status.ReplayOf)
+	}
+
+	if _, err := newExecutionJob(CompileRequest{Replay: "../config"}); err == nil {
+		t.Error("replayed a job by path")
+	}
+}
+
+func TestRunReplayReportsDifferences(t *testing.T) {
+	recorded := recordJob(t)
+	path := filepath.Join(replayDir, recorded.ID+".json")
+	if code := runReplay(path); code != 0 {
+		t.Fatalf("unchanged replay exited %d", code)
+	}
+
+	// Pretend the first response used to be classified differently and
+	// the second was asked for with another prompt
+	bundle, err := loadBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle.Candidates[0].ErrorType = "syntax"
+	bundle.Exchanges[1].Request.Messages[3].Content = "Fix it."
+	if _, err := saveBundle(bundle); err != nil {
+		t.Fatal(err)
+	}
+	if code := runReplay(path); code != 1 {
+		t.Errorf("changed replay exited %d, want 1", code)
+	}
+
+	replayed, _ := replayJob(bundle, false)
+	RunCompilationJob(replayed, newJobSink(replayed, nil))
+	diffs := replayed.snapshot().Data.(jobStatusData).ReplayDiffs
+	want := []string{
+		"1 LLM request(s) differed from the recording",
+		`iteration 1 candidate 0 error type: "syntax", now "type"`,
+	}
+	if strings.Join(diffs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("differences:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runReplay(path); code != 2 {
+		t.Errorf("unreadable bundle exited %d, want 2", code)
+	}
+}
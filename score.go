@@ -0,0 +1,67 @@
+package main
+
+// ============================================================================
+// SCORING
+// ============================================================================
+
+// Score summarizes how close a result is to passing, for ranking candidates
+type Score struct {
+	Success     bool `json:"success"`
+	Builds      bool `json:"builds"` // Compiled; only tests failed or crashed
+	TestsPassed int  `json:"testsPassed"`
+	TestsTotal  int  `json:"testsTotal"`
+	Diagnostics int  `json:"diagnostics"` // Errors reported, fewer is better
+}
+
+// Solution is a scored iteration's code, kept so a job can hand back its
+// best work even when it never passes
+type Solution struct {
+	Iteration int    `json:"iteration"`
+	MainCode  string `json:"mainCode"`
+	TestCode  string `json:"testCode"`
+	ErrorType string `json:"errorType"`
+	Score     Score  `json:"score"`
+}
+
+// scoreResult scores a compilation result
+func scoreResult(result *CompilationResult) Score {
+	if result.Success {
+		s := Score{Success: true, Builds: true}
+		if result.Tests != nil {
+			s.TestsPassed, s.TestsTotal = result.Tests.Passed, result.Tests.Passed+result.Tests.Failed
+		}
+		return s
+	}
+
+	s := Score{
+		Builds:      result.ErrorType == ErrorTypeLogic || result.ErrorType == ErrorTypeRuntime,
+		Diagnostics: len(failureMessages(result)),
+	}
+	if result.Tests != nil {
+		s.TestsPassed, s.TestsTotal = result.Tests.Passed, result.Tests.Passed+result.Tests.Failed
+	}
+	return s
+}
+
+// PassRatio is the fraction of tests that passed, 0 if none ran
+func (s Score) PassRatio() float64 {
+	if s.TestsTotal == 0 {
+		return 0
+	}
+	return float64(s.TestsPassed) / float64(s.TestsTotal)
+}
+
+// Better reports whether s ranks above o: passing beats building, building
+// beats not building, then more of the tests passing, then fewer errors
+func (s Score) Better(o Score) bool {
+	if s.Success != o.Success {
+		return s.Success
+	}
+	if s.Builds != o.Builds {
+		return s.Builds
+	}
+	if s.PassRatio() != o.PassRatio() {
+		return s.PassRatio() > o.PassRatio()
+	}
+	return s.Diagnostics < o.Diagnostics
+}
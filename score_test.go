@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestScoreRanking(t *testing.T) {
+	passing := scoreResult(&CompilationResult{Success: true, ErrorType: ErrorTypeSuccess, Tests: &TestReport{Passed: 3}})
+	mostTests := scoreResult(&CompilationResult{ErrorType: ErrorTypeLogic, TestErrors: []string{"x"}, Tests: &TestReport{Passed: 2, Failed: 1}})
+	fewTests := scoreResult(&CompilationResult{ErrorType: ErrorTypeLogic, TestErrors: []string{"x", "y"}, Tests: &TestReport{Passed: 1, Failed: 2}})
+	crashes := scoreResult(&CompilationResult{ErrorType: ErrorTypeRuntime, TestErrors: []string{"panic"}})
+	oneError := scoreResult(&CompilationResult{ErrorType: ErrorTypeType, CompileErrors: []string{"undefined: x"}})
+	twoErrors := scoreResult(&CompilationResult{ErrorType: ErrorTypeSyntax, CompileErrors: []string{"a", "b"}})
+
+	// Best first
+	ranked := []Score{passing, mostTests, fewTests, crashes, oneError, twoErrors}
+	for i := 0; i < len(ranked)-1; i++ {
+		if !ranked[i].Better(ranked[i+1]) || ranked[i+1].Better(ranked[i]) {
+			t.Errorf("score %d %+v should rank above %d %+v", i, ranked[i], i+1, ranked[i+1])
+		}
+	}
+	if passing.Better(passing) {
+		t.Error("a score is not better than itself")
+	}
+	if mostTests.TestsTotal != 3 || mostTests.PassRatio() != 2.0/3 {
+		t.Errorf("mostTests = %+v", mostTests)
+	}
+}
+
+func TestRecordSolution(t *testing.T) {
+	job, err := newExecutionJob(CompileRequest{Prompt: "add two numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Cancel()
+
+	attempt := func(code string, passed, failed int) *candidate {
+		result := &CompilationResult{ErrorType: ErrorTypeLogic, TestErrors: []string{"fail"}, Tests: &TestReport{Passed: passed, Failed: failed}}
+		return &candidate{MainCode: code, Result: result, Score: scoreResult(result)}
+	}
+	job.recordSolution(1, attempt("first", 1, 2))
+	job.recordSolution(2, attempt("second", 2, 1))
+	job.recordSolution(3, attempt("third", 0, 3))
+	job.recordSolution(4, attempt("fourth", 2, 1))
+
+	best := job.snapshot().Data.(jobStatusData).Best
+	if best == nil || best.Iteration != 2 || best.MainCode != "second" || best.ErrorType != "logic" {
+		t.Errorf("Best = %+v, want iteration 2", best)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// MessageSink receives the messages a job produces while it runs
+type MessageSink interface {
+	Send(msg WSMessage) error
+}
+
+// wsSink writes messages to a WebSocket. gorilla/websocket allows only one
+// concurrent writer, so writes are serialized.
+type wsSink struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func newWSSink(conn *websocket.Conn) *wsSink {
+	return &wsSink{conn: conn}
+}
+
+func (s *wsSink) Send(msg WSMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteJSON(msg)
+}
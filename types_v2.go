@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"llama/modules/language"
+	"llama/modules/llm"
+	ollamaimplementation "llama/modules/ollama-implementation"
+	"sync"
 	"time"
 )
 
@@ -9,78 +13,98 @@ import (
 // CORE TYPES
 // ============================================================================
 
-// ErrorType classifies the nature of a compilation/execution error
-type ErrorType int
+// ErrorType and CompilationResult are shared with the language backends
+type ErrorType = language.ErrorType
 
 const (
-	ErrorTypeUnknown        ErrorType = iota
-	ErrorTypeInfrastructure           // go.mod exists, missing dependencies, etc.
-	ErrorTypeSyntax                   // Parse error, invalid syntax
-	ErrorTypeType                     // Type mismatch, undefined symbols
-	ErrorTypeLogic                    // Test failures, logic errors
-	ErrorTypeRuntime                  // Crashes, segfaults, panics
-	ErrorTypeSuccess                  // No error
+	ErrorTypeUnknown        = language.ErrorTypeUnknown
+	ErrorTypeInfrastructure = language.ErrorTypeInfrastructure
+	ErrorTypeSyntax         = language.ErrorTypeSyntax
+	ErrorTypeType           = language.ErrorTypeType
+	ErrorTypeLogic          = language.ErrorTypeLogic
+	ErrorTypeRuntime        = language.ErrorTypeRuntime
+	ErrorTypeSuccess        = language.ErrorTypeSuccess
 )
 
-func (e ErrorType) String() string {
-	switch e {
-	case ErrorTypeInfrastructure:
-		return "infrastructure"
-	case ErrorTypeSyntax:
-		return "syntax"
-	case ErrorTypeType:
-		return "type"
-	case ErrorTypeLogic:
-		return "logic"
-	case ErrorTypeRuntime:
-		return "runtime"
-	case ErrorTypeSuccess:
-		return "success"
-	default:
-		return "unknown"
-	}
-}
+type CompilationResult = language.CompilationResult
 
-// CompilationResult holds the output of a single compilation attempt
-type CompilationResult struct {
-	Success       bool
-	ExitCode      int
-	CompileErrors []string // Compilation-time errors
-	TestErrors    []string // Test-time failures
-	Output        string   // Raw combined output
-	ErrorType     ErrorType
-	ExecutionTime time.Duration
-}
+type Diagnostic = language.Diagnostic
+
+type StageResult = language.StageResult
+
+type TestReport = language.TestReport
+
+type AutoFix = language.AutoFix
 
 // ExecutionMetrics tracks performance and behavior across iterations
 type ExecutionMetrics struct {
 	IterationCount     int
 	TotalTime          time.Duration
 	PromptSizes        []int           // Prompt size per iteration
+	PromptTokens       []int           // Estimated prompt tokens per iteration
 	LLMResponseTimes   []time.Duration // LLM latency per iteration
 	LastErrorType      ErrorType
-	SameErrorCount     int      // Consecutive identical errors
-	ExtractedLanguages []string // Main, Test
+	ErrorSubtypeCounts map[string]int // Failures per error subtype, e.g. "unused_import"
+	AutoFixCounts      map[string]int // Rewrites per auto-fix, e.g. "remove_unused_import"
+	PatchesApplied     int            // Iterations whose code came from a diff
+	PatchFallbacks     int            // Iterations regenerated in full because the diff didn't apply
+	SameErrorCount     int            // Consecutive identical errors
+	ExtractedLanguages []string       // Main, Test
 }
 
 // LLMContext maintains stateful conversation with the LLM
 type LLMContext struct {
-	ConversationTokens []int       // Ollama context for multi-turn conversation
-	PromptHistory      []string    // Track all prompts sent to LLM
-	ErrorHistory       []ErrorType // Track error types seen
-	AttemptCount       int
-	LastErrorMessage   string
+	Messages         []ollamaimplementation.Message // Conversation so far, oldest first, without the system prompt
+	PromptHistory    []string                       // Track all prompts sent to LLM
+	ErrorHistory     []ErrorType                    // Track error types seen
+	AttemptCount     int
+	LastErrorMessage string
+	ContextResets    int       // Times Messages was dropped to stay within budget
+	Attempts         []Attempt // Failed attempts, oldest first
+}
+
+// Attempt is the code generated in one iteration and what it compiled to
+type Attempt struct {
+	Iteration int
+	MainCode  string
+	TestCode  string
+	Result    *CompilationResult
+
+	Fingerprint string // Normalized identity of the failure, see errorFingerprint
+	ErrorCount  int
+}
+
+// LastAttempt returns the most recent attempt, or nil before the first one
+func (c *LLMContext) LastAttempt() *Attempt {
+	if len(c.Attempts) == 0 {
+		return nil
+	}
+	return &c.Attempts[len(c.Attempts)-1]
 }
 
 // ExecutionJob represents a single user request being processed
 type ExecutionJob struct {
 	ID            string
 	Language      string
+	Lang          language.Language // Backend resolved from Language
 	UserPrompt    string
+	SystemPrompt  string // Sent ahead of the conversation, followed by the language's format rules
 	Model         string
 	MaxIterations int
+	Candidates    int                           // Generated per iteration, 1 to MaxCandidates
+	PatchMode     bool                          // Ask for diffs against the last attempt when repairing
+	Options       *ollamaimplementation.Options // Model defaults with the request's options on top, nil for none
+	Provider      string                        // Configured provider the job's LLM requests go to
+	LLM           llm.LLMClient
 	Timeout       time.Duration
 
+	// Recorder wraps LLM while the job is recorded or replayed, and the
+	// bundle is saved when it ends if Record is set. Replay is the bundle
+	// being replayed, nil for a job that asks a real LLM.
+	Recorder *llm.Recorder
+	Record   bool
+	Replay   *Bundle
+
 	Ctx       context.Context
 	Cancel    context.CancelFunc
 	Status    string // "pending", "running", "completed", "aborted"
@@ -89,8 +113,22 @@ type ExecutionJob struct {
 	LLMCtx  LLMContext
 	Metrics ExecutionMetrics
 
+	// StdlibOnly is set once imports can't be resolved even offline; from
+	// then on prompts ask for standard library code only
+	StdlibOnly bool
+
 	FinalResult *CompilationResult
+	Best        *Solution // Highest scoring iteration so far, nil before the first compiles
 	AbortReason string
+	StuckReason string // Why stuck detection gave up, if it did
+	EndTime     time.Time
+
+	// mu guards the fields above that are read by the job API while the
+	// pipeline goroutine is still writing them, and messages.
+	mu          sync.RWMutex
+	messages    []WSMessage
+	recorded    []BundleCandidate
+	replayDiffs []string
 }
 
 // ============================================================================
@@ -104,7 +142,10 @@ const (
 	DefaultCompileTimeout   = 30 * time.Second
 	MaxPromptSize           = 50 * 1024 // 50KB
 	SameErrorThreshold      = 3         // Abort if same error 3x
-	MaxPromptSizeGrowthRate = 1.5       // Abort if prompt grows by 1.5x
+	OscillationMaxPeriod    = 3         // Longest error cycle detected (A-B-C-A-B-C)
+	NoProgressWindow        = 5         // Abort if this many attempts never improve on the first
+	MaxCandidates           = 4         // Upper bound on candidates per iteration
+	MaxPromptSizeGrowthRate = 1.5       // Abort if a compacted repair prompt still grows by 1.5x
 )
 
 // ============================================================================
@@ -118,6 +159,20 @@ const (
 	WSTypeCompletion WSMessageType = "completion"
 	WSTypeAbort      WSMessageType = "abort"
 	WSTypeError      WSMessageType = "error"
+	WSTypeRecovery   WSMessageType = "recovery"
+	WSTypeCandidate  WSMessageType = "candidate"
+	WSTypeToken      WSMessageType = "token"
+	WSTypePhase      WSMessageType = "phase"
+	WSTypePull       WSMessageType = "pull"
+	WSTypeRecording  WSMessageType = "recording"
+)
+
+// Phases a candidate goes through in an iteration, reported as they start
+const (
+	PhaseGenerating = "generating"
+	PhaseExtracting = "extracting"
+	PhaseCompiling  = language.PhaseCompiling
+	PhaseTesting    = language.PhaseTesting
 )
 
 type WSIterationData struct {
@@ -128,24 +183,108 @@ type WSIterationData struct {
 	CompilerOutput       string `json:"compilerOutput"`
 	CompiledSuccessfully bool   `json:"compiledSuccessfully"`
 	ErrorType            string `json:"errorType"`
+	ErrorSubtype         string `json:"errorSubtype,omitempty"`
+	Stage                string `json:"stage,omitempty"` // Build/test stage that failed
 	ElapsedSeconds       int    `json:"elapsedSeconds"`
 	PromptSize           int    `json:"promptSize"`
+	PromptTokens         int    `json:"promptTokens"` // Estimated, see estimateTokens
 	LLMResponseTime      int    `json:"llmResponseTime"`
+
+	Diagnostics []Diagnostic  `json:"diagnostics,omitempty"`
+	Stages      []StageResult `json:"stages,omitempty"`
+	Tests       *TestReport   `json:"tests,omitempty"`
+	Fingerprint string        `json:"fingerprint,omitempty"`
+	AutoFixes   []AutoFix     `json:"autoFixes,omitempty"`  // Rewrites made before compiling
+	Candidate   int           `json:"candidate"`            // Index of the candidate carried forward
+	Candidates  int           `json:"candidates,omitempty"` // Candidates generated, when more than one
+	Score       *Score        `json:"score,omitempty"`
+	Patch       string        `json:"patch,omitempty"`      // Diff applied to the previous code, in patch mode
+	PatchError  string        `json:"patchError,omitempty"` // Why the diff wasn't applied and the code was regenerated
+}
+
+// WSCandidateData reports one of several candidates generated in an iteration
+type WSCandidateData struct {
+	Iteration   int       `json:"iteration"`
+	Candidate   int       `json:"candidate"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	Status      string    `json:"status"` // "compiled", or why it didn't, e.g. "extraction_failed"
+	Error       string    `json:"error,omitempty"`
+	ErrorType   string    `json:"errorType,omitempty"`
+	Score       *Score    `json:"score,omitempty"`
+	MainCode    string    `json:"mainCode,omitempty"`
+	TestCode    string    `json:"testCode,omitempty"`
+	AutoFixes   []AutoFix `json:"autoFixes,omitempty"`
+	Patch       string    `json:"patch,omitempty"`
+	PatchError  string    `json:"patchError,omitempty"`
+}
+
+// WSTokenData carries a piece of an LLM response as it is generated
+type WSTokenData struct {
+	Iteration int    `json:"iteration"`
+	Candidate int    `json:"candidate"`
+	Text      string `json:"text"`
+}
+
+// WSPhaseData reports a candidate moving on to another phase. A new
+// "generating" phase starts a new response, e.g. when a patch couldn't be
+// applied and the full code is asked for instead.
+type WSPhaseData struct {
+	Iteration int    `json:"iteration"`
+	Candidate int    `json:"candidate"`
+	Phase     string `json:"phase"` // One of the Phase constants
+}
+
+// WSPullData reports the progress of a model download
+type WSPullData struct {
+	Model     string `json:"model"`
+	Status    string `json:"status,omitempty"` // As Ollama reports it, e.g. "pulling manifest"
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"` // Bytes of the layer being downloaded
+	Completed int64  `json:"completed,omitempty"`
+	Done      bool   `json:"done"`
+	Error     string `json:"error,omitempty"`
+}
+
+// WSRecordingData follows the completion or abort message of a job that was
+// recorded or replayed
+type WSRecordingData struct {
+	JobID       string   `json:"jobId"`              // What to replay the job by, if Saved
+	Saved       bool     `json:"saved"`              // Whether a bundle was written
+	Error       string   `json:"error,omitempty"`    // Why it wasn't, if one was asked for
+	ReplayOf    string   `json:"replayOf,omitempty"` // The recorded job, for a replay
+	Differences []string `json:"differences,omitempty"`
+}
+
+// WSRecoveryData reports one attempt to get past an infrastructure error
+type WSRecoveryData struct {
+	Iteration int    `json:"iteration"`
+	Action    string `json:"action"`    // e.g. "fresh_workspace", "offline_tidy", "stdlib_only"
+	Recovered bool   `json:"recovered"` // The code got past the infrastructure error
+	ErrorType string `json:"errorType"` // Of the result after the action
+	Message   string `json:"message"`
 }
 
 type WSCompletionData struct {
-	FinalStatus     string `json:"finalStatus"` // "success" or "aborted"
-	TotalIterations int    `json:"totalIterations"`
-	TotalTime       string `json:"totalTime"`
-	Code            string `json:"code"`
-	Tests           string `json:"tests"`
+	FinalStatus     string    `json:"finalStatus"` // "success" or "aborted"
+	TotalIterations int       `json:"totalIterations"`
+	TotalTime       string    `json:"totalTime"`
+	Code            string    `json:"code"`
+	Tests           string    `json:"tests"`
+	Best            *Solution `json:"best,omitempty"`
 }
 
 type WSAbortData struct {
-	Reason        string `json:"reason"` // "max_iterations", "same_error_3x", "timeout", "llm_error"
-	Iteration     int    `json:"iteration"`
-	LastError     string `json:"lastError"`
-	LastErrorType string `json:"lastErrorType"`
+	Reason        string    `json:"reason"` // e.g. "max_iterations_reached", "repeated_error", "oscillating_errors", "total_timeout"
+	Iteration     int       `json:"iteration"`
+	LastError     string    `json:"lastError"`
+	LastErrorType string    `json:"lastErrorType"`
+	StuckReason   string    `json:"stuckReason,omitempty"`
+	Best          *Solution `json:"best,omitempty"` // Best code produced before giving up
+}
+
+type WSErrorData struct {
+	Message string `json:"message"`
 }
 
 type WSMessage struct {
@@ -153,16 +292,67 @@ type WSMessage struct {
 	Data interface{}   `json:"data"`
 }
 
+// ============================================================================
+// CLIENT MESSAGES FOR WEBSOCKET
+// ============================================================================
+
+type WSClientMessageType string
+
+const (
+	WSClientStart  WSClientMessageType = "start"
+	WSClientCancel WSClientMessageType = "cancel"
+	WSClientPull   WSClientMessageType = "pull" // Download Model on Provider's Ollama server
+)
+
+// WSClientMessage is sent by the browser. A message without a type is
+// treated as "start" so older clients that only send prompt/model still work.
+type WSClientMessage struct {
+	Type          WSClientMessageType           `json:"type"`
+	Language      string                        `json:"language"`
+	Prompt        string                        `json:"prompt"`
+	Model         string                        `json:"model"`
+	MaxIterations int                           `json:"maxIterations"`
+	Candidates    int                           `json:"candidates"`
+	PatchMode     bool                          `json:"patchMode"`
+	Options       *ollamaimplementation.Options `json:"options,omitempty"`
+	SystemPrompt  string                        `json:"systemPrompt,omitempty"`
+	Provider      string                        `json:"provider,omitempty"`
+	Record        bool                          `json:"record,omitempty"`
+	Replay        string                        `json:"replay,omitempty"`
+	Timeout       int                           `json:"timeout"` // seconds
+}
+
 // ============================================================================
 // HTTP API TYPES
 // ============================================================================
 
 type CompileRequest struct {
-	Language      string `json:"language"` // "go", "python", "cpp"
-	Prompt        string `json:"prompt"`
-	Model         string `json:"model"`
-	MaxIterations int    `json:"maxIterations"`
-	Timeout       int    `json:"timeout"` // seconds
+	Language      string                        `json:"language"` // "go", "python", "cpp"
+	Prompt        string                        `json:"prompt"`
+	Model         string                        `json:"model"`
+	MaxIterations int                           `json:"maxIterations"`
+	Candidates    int                           `json:"candidates"`             // Per iteration, default 1
+	PatchMode     bool                          `json:"patchMode"`              // Repair with diffs instead of full regeneration
+	Options       *ollamaimplementation.Options `json:"options,omitempty"`      // Generation options, over the model's defaults
+	SystemPrompt  string                        `json:"systemPrompt,omitempty"` // Replaces the configured system prompt
+	Provider      string                        `json:"provider,omitempty"`     // Configured provider, default the configured default
+	Record        bool                          `json:"record,omitempty"`       // Save a replay bundle when the job ends
+	Replay        string                        `json:"replay,omitempty"`       // ID of a recorded job to rerun from its bundle; other fields are ignored
+	Timeout       int                           `json:"timeout"`                // seconds
+}
+
+// ModelsResponse is the body of GET /api/models
+type ModelsResponse struct {
+	Models []ModelSummary `json:"models"`
+}
+
+// ModelSummary describes an installed model
+type ModelSummary struct {
+	Name          string `json:"name"`
+	Size          int64  `json:"size"` // Bytes on disk
+	Family        string `json:"family"`
+	ParameterSize string `json:"parameterSize,omitempty"`
+	Quantization  string `json:"quantization,omitempty"`
 }
 
 type CompileResponse struct {
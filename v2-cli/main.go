@@ -2,11 +2,15 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"llama/modules/compiler_v2/go_compiler_v2"
 	displayindicator "llama/modules/display-indicator"
 	"llama/modules/extraction"
-	ollamaimplementation "llama/modules/ollama-implementation"
+	"llama/modules/llm"
 	"os"
 	"strings"
 	"time"
@@ -15,18 +19,31 @@ import (
 var startTime time.Time = time.Now()
 var model string = "codellama"
 
+// llmTimeout bounds a single request to the LLM
+const llmTimeout = 5 * time.Minute
+
 type compilerFunc func(string, string) ([]byte, error)
 
 func main() {
+	provider := flag.String("provider", llm.ProviderOllama, "LLM provider: ollama, openai or scripted")
+	endpoint := flag.String("endpoint", "", "Provider base URL (default http://localhost:11434 for ollama)")
+	script := flag.String("script", "", "JSON file with a list of canned responses for the scripted provider")
+	flag.StringVar(&model, "model", model, "Model to generate with")
+	flag.Parse()
+
+	client, err := newLLMClient(*provider, *endpoint, *script)
+	if err != nil {
+		fmt.Println("Error setting up the LLM provider:", err)
+		os.Exit(1)
+	}
 
 	reader := bufio.NewReader(os.Stdin)
-	var conversationContext []int // Variable to store conversation context
 
 	fmt.Print("Enter your prompt (or type 'exit' to quit): ")
 	userPrompt, _ := reader.ReadString('\n')
 	userPrompt = strings.TrimSpace(userPrompt)
 
-	RunProgram(userPrompt, conversationContext, extraction.GoPrompt, go_compiler_v2.NewGoCompiler().CheckCompileErrors)
+	RunProgram(userPrompt, extraction.GoPrompt, go_compiler_v2.NewGoCompiler().CheckCompileErrors, client)
 
 	// Go routines dont seem to be executing at the moment, so will figure this out later.
 	// go RunProgram(userPrompt, conversationContext, extraction.GoPrompt, go_compiler_v2.NewGoCompiler().CheckCompileErrors)
@@ -38,8 +55,24 @@ func main() {
 	fmt.Println("Total Execution Time:", diff)
 }
 
-func RunProgram(userPrompt string, conversationContext []int, languagePrompt string, compiler compilerFunc) {
-	currentConversationContext := conversationContext
+// newLLMClient returns a client for the provider named on the command line.
+// An openai provider's API key is read from OPENAI_API_KEY.
+func newLLMClient(provider, endpoint, script string) (llm.LLMClient, error) {
+	config := llm.Config{Type: provider, Endpoint: endpoint, APIKey: os.Getenv("OPENAI_API_KEY")}
+	if script != "" {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &config.Responses); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", script, err)
+		}
+	}
+	return llm.New(config, llmTimeout)
+}
+
+func RunProgram(userPrompt string, languagePrompt string, compiler compilerFunc, client llm.LLMClient) {
+	var conversation []llm.Message // Every prompt and response so far
 	var numOfIterations uint = 1
 
 	for {
@@ -50,19 +83,25 @@ func RunProgram(userPrompt string, conversationContext []int, languagePrompt str
 		done := make(chan bool)
 		go displayindicator.DisplayLoadingIndicator(done)
 
-		// Generate response using Ollama API, passing the context
-		response, updatedContext, err := ollamaimplementation.GetOllamaResponse(modifiedPrompt, currentConversationContext, model)
+		// Generate response, continuing the conversation
+		messages := append(conversation, llm.Message{Role: llm.RoleUser, Content: modifiedPrompt})
+		reply, err := client.Complete(context.Background(), llm.Request{Model: model, Messages: messages})
 
 		// Signal the waiting indicator to stop
 		done <- true
 
+		if errors.Is(err, llm.ErrScriptExhausted) {
+			fmt.Println("Error generating response:", err)
+			return
+		}
 		if err != nil {
 			fmt.Println("Error generating response:", err)
 			continue
 		}
 
-		// Update the conversation context with the response
-		currentConversationContext = updatedContext
+		// Update the conversation with the response
+		conversation = append(messages, reply)
+		response := reply.Content
 
 		generatedCode, testCode, errExtract := extraction.Extract(response) // Handle error with string
 		if errExtract != nil {
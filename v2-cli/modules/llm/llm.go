@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Roles of the messages in a conversation
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request asks Model for the next message of a conversation
+type Request struct {
+	Model    string
+	Messages []Message
+}
+
+// LLMClient is a provider that can continue a conversation
+type LLMClient interface {
+	Complete(ctx context.Context, req Request) (Message, error)
+}
+
+// Provider types
+const (
+	ProviderOllama   = "ollama"   // An Ollama server's chat endpoint
+	ProviderOpenAI   = "openai"   // An OpenAI-compatible chat completions endpoint
+	ProviderScripted = "scripted" // Canned responses, for running without a model
+)
+
+// Config selects a provider
+type Config struct {
+	Type      string
+	Endpoint  string   // Base URL; Ollama's defaults to DefaultOllamaEndpoint
+	APIKey    string   // OpenAI-compatible only
+	Responses []string // Scripted only
+}
+
+// DefaultOllamaEndpoint is where a local Ollama server listens
+const DefaultOllamaEndpoint = "http://localhost:11434"
+
+// New returns a client for c whose requests give up after timeout
+func New(c Config, timeout time.Duration) (LLMClient, error) {
+	httpClient := &http.Client{Timeout: timeout}
+	switch c.Type {
+	case ProviderOllama, "":
+		endpoint := c.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultOllamaEndpoint
+		}
+		return &Ollama{Endpoint: endpoint, HTTPClient: httpClient}, nil
+	case ProviderOpenAI:
+		if c.Endpoint == "" {
+			return nil, errors.New("an openai provider needs an endpoint")
+		}
+		return &OpenAI{Endpoint: c.Endpoint, APIKey: c.APIKey, HTTPClient: httpClient}, nil
+	case ProviderScripted:
+		return NewScripted(c.Responses...), nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q", c.Type)
+}
+
+// postJSON sends body to url and decodes the response into out, returning
+// the server's message for a failed request
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
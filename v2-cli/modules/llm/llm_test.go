@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string    `json:"model"`
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "codellama" || len(req.Messages) != 1 {
+			t.Errorf("%s request = %+v", r.URL.Path, req)
+		}
+		switch r.URL.Path {
+		case "/api/chat":
+			w.Write([]byte(`{"message":{"role":"assistant","content":"from ollama"},"done":true}`))
+		case "/v1/chat/completions":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				t.Errorf("authorization %q", r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"from openai"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	req := Request{Model: "codellama", Messages: []Message{{Role: RoleUser, Content: "hi"}}}
+	for _, c := range []Config{
+		{Type: ProviderOllama, Endpoint: server.URL},
+		{Type: ProviderOpenAI, Endpoint: server.URL, APIKey: "secret"},
+		{Type: ProviderScripted, Responses: []string{"from scripted"}},
+	} {
+		client, err := New(c, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg, err := client.Complete(context.Background(), req); err != nil || msg.Content != "from "+c.Type {
+			t.Errorf("%s: %+v, %v", c.Type, msg, err)
+		}
+	}
+
+	if _, err := New(Config{Type: ProviderOpenAI}, time.Minute); err == nil {
+		t.Error("openai provider without an endpoint was accepted")
+	}
+}
+
+func TestScriptedExhausted(t *testing.T) {
+	s := NewScripted("only")
+	s.Complete(context.Background(), Request{})
+	if _, err := s.Complete(context.Background(), Request{}); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("err = %v, want ErrScriptExhausted", err)
+	}
+	if n := len(s.Requests()); n != 2 {
+		t.Errorf("recorded %d requests, want 2", n)
+	}
+}
@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Ollama is the provider for an Ollama server's chat endpoint
+type Ollama struct {
+	Endpoint   string // Base URL, e.g. http://localhost:11434
+	HTTPClient *http.Client
+}
+
+type ollamaChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Message Message `json:"message"`
+	Error   string  `json:"error,omitempty"`
+}
+
+func (o *Ollama) Complete(ctx context.Context, req Request) (Message, error) {
+	var resp ollamaChatResponse
+	err := postJSON(ctx, o.HTTPClient, strings.TrimSuffix(o.Endpoint, "/")+"/api/chat", "",
+		ollamaChatRequest{Model: req.Model, Messages: req.Messages}, &resp)
+	if err != nil {
+		return Message{}, err
+	}
+	if resp.Error != "" {
+		return Message{}, errors.New(resp.Error)
+	}
+	return resp.Message, nil
+}
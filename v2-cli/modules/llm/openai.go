@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// OpenAI is the provider for servers implementing OpenAI's chat completions
+// API, such as llama.cpp's server and vLLM
+type OpenAI struct {
+	Endpoint   string // Base URL, e.g. http://localhost:8080 or http://localhost:8000/v1
+	APIKey     string // Sent as a bearer token if set
+	HTTPClient *http.Client
+}
+
+type openAIRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+}
+
+func (o *OpenAI) Complete(ctx context.Context, req Request) (Message, error) {
+	url := strings.TrimSuffix(o.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1") {
+		url += "/v1"
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, o.HTTPClient, url+"/chat/completions", o.APIKey, openAIRequest{Model: req.Model, Messages: req.Messages}, &resp); err != nil {
+		return Message{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return Message{}, errors.New("response has no choices")
+	}
+	return resp.Choices[0].Message, nil
+}